@@ -0,0 +1,70 @@
+package main
+
+import (
+	"testing"
+)
+
+//go:generate go run ./cmd/bingen -type AgentDataBinary,binaryKinds
+
+// AgentDataBinary has the fields of AgentData, with generated
+// MarshalBinary and UnmarshalBinary methods in
+// agentdatabinary_binary_test.go. AgentData itself keeps none, since gob
+// would otherwise use them in BenchmarkGobMarshal.
+type AgentDataBinary struct {
+	Hostname  string
+	Status    string
+	Timestamp int
+	Lsns      []string
+}
+
+// binaryKinds has a field of every kind bingen supports, for the generated
+// round-trip test.
+type binaryKinds struct {
+	S       string
+	B       bool
+	I       int
+	I8      int8
+	I16     int16
+	I32     int32
+	I64     int64
+	U       uint
+	U8      uint8
+	U16     uint16
+	U32     uint32
+	U64     uint64
+	R       rune
+	Bs      []bool
+	I8s     []int8
+	U8s     []byte
+	Us      []uint64
+	skipped int
+	Skipped string `bin:"-"`
+}
+
+func BenchmarkGeneratedBinaryMarshal(b *testing.B) {
+	obj := AgentDataBinary(*generateObject())
+	var out []byte
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		out, _ = obj.AppendBinary(out[:0])
+	}
+	b.ReportMetric(float64(len(out)), "bytes")
+}
+
+func BenchmarkGeneratedBinaryUnmarshal(b *testing.B) {
+	out, err := AgentDataBinary(*generateObject()).MarshalBinary()
+	if err != nil {
+		panic(err)
+	}
+
+	obj := &AgentDataBinary{}
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		err := obj.UnmarshalBinary(out)
+		if err != nil {
+			panic(err)
+		}
+	}
+}
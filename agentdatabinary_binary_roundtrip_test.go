@@ -0,0 +1,116 @@
+// Code generated by bingen -type AgentDataBinary,binaryKinds; DO NOT EDIT.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAgentDataBinaryBinaryRoundTrip(t *testing.T) {
+	for _, want := range []AgentDataBinary{
+		{},
+		{
+			Hostname:  "\x00\xff",
+			Status:    "\x00\xff",
+			Timestamp: int(-1 << 31),
+			Lsns:      []string{"\x00\xff", "é, and then some"},
+		},
+		{
+			Hostname:  "é, and then some",
+			Status:    "é, and then some",
+			Timestamp: int(1<<31 - 1),
+			Lsns:      []string{"é, and then some", "é, and then some"},
+		},
+	} {
+		data, err := want.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(data) != want.SizeBinary() {
+			t.Errorf("%+v: encoded %d bytes, SizeBinary %d", want, len(data), want.SizeBinary())
+		}
+		var got AgentDataBinary
+		if err := got.UnmarshalBinary(data); err != nil {
+			t.Fatalf("%+v: %v", want, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %+v, want %+v", got, want)
+		}
+		for n := 0; n < len(data); n++ {
+			if err := got.UnmarshalBinary(data[:n]); err == nil {
+				t.Errorf("%+v: %d of %d bytes decoded without error", want, n, len(data))
+			}
+		}
+		if err := got.UnmarshalBinary(append(data, 0)); err == nil {
+			t.Errorf("%+v: trailing data decoded without error", want)
+		}
+	}
+}
+
+func TestBinaryKindsBinaryRoundTrip(t *testing.T) {
+	for _, want := range []binaryKinds{
+		{},
+		{
+			S:   "\x00\xff",
+			B:   false,
+			I:   int(-1 << 31),
+			I8:  int8(-1 << 7),
+			I16: int16(-1 << 15),
+			I32: int32(-1 << 31),
+			I64: int64(-1 << 63),
+			U:   uint(0),
+			U8:  uint8(0),
+			U16: uint16(0),
+			U32: uint32(0),
+			U64: uint64(0),
+			R:   rune(-1 << 31),
+			Bs:  []bool{false, true},
+			I8s: []int8{-1 << 7, 1<<7 - 1},
+			U8s: []byte{0, 1<<8 - 1},
+			Us:  []uint64{0, 1<<64 - 1},
+		},
+		{
+			S:   "é, and then some",
+			B:   true,
+			I:   int(1<<31 - 1),
+			I8:  int8(1<<7 - 1),
+			I16: int16(1<<15 - 1),
+			I32: int32(1<<31 - 1),
+			I64: int64(1<<63 - 1),
+			U:   uint(1<<32 - 1),
+			U8:  uint8(1<<8 - 1),
+			U16: uint16(1<<16 - 1),
+			U32: uint32(1<<32 - 1),
+			U64: uint64(1<<64 - 1),
+			R:   rune(1<<31 - 1),
+			Bs:  []bool{true, true},
+			I8s: []int8{1<<7 - 1, 1<<7 - 1},
+			U8s: []byte{1<<8 - 1, 1<<8 - 1},
+			Us:  []uint64{1<<64 - 1, 1<<64 - 1},
+		},
+	} {
+		data, err := want.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(data) != want.SizeBinary() {
+			t.Errorf("%+v: encoded %d bytes, SizeBinary %d", want, len(data), want.SizeBinary())
+		}
+		var got binaryKinds
+		if err := got.UnmarshalBinary(data); err != nil {
+			t.Fatalf("%+v: %v", want, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %+v, want %+v", got, want)
+		}
+		for n := 0; n < len(data); n++ {
+			if err := got.UnmarshalBinary(data[:n]); err == nil {
+				t.Errorf("%+v: %d of %d bytes decoded without error", want, n, len(data))
+			}
+		}
+		if err := got.UnmarshalBinary(append(data, 0)); err == nil {
+			t.Errorf("%+v: trailing data decoded without error", want)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+// Code generated by bingen -type AgentDataBinary,binaryKinds; DO NOT EDIT.
+
+package main
+
+import "github.com/evaluate_serde_protocol/binwire"
+
+// SizeBinary returns the length of the binary encoding of v.
+func (v AgentDataBinary) SizeBinary() int {
+	n := 0
+	n += binwire.SizeString(v.Hostname)
+	n += binwire.SizeString(v.Status)
+	n += binwire.SizeVarint(int64(v.Timestamp))
+	n += binwire.SizeUvarint(uint64(len(v.Lsns)))
+	for _, e := range v.Lsns {
+		n += binwire.SizeString(e)
+	}
+	return n
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (v AgentDataBinary) MarshalBinary() ([]byte, error) {
+	return v.AppendBinary(make([]byte, 0, v.SizeBinary()))
+}
+
+// AppendBinary appends the binary encoding of v to b.
+func (v AgentDataBinary) AppendBinary(b []byte) ([]byte, error) {
+	b = binwire.AppendString(b, v.Hostname)
+	b = binwire.AppendString(b, v.Status)
+	b = binwire.AppendVarint(b, int64(v.Timestamp))
+	b = binwire.AppendUvarint(b, uint64(len(v.Lsns)))
+	for _, e := range v.Lsns {
+		b = binwire.AppendString(b, e)
+	}
+	return b, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler. Slices reuse
+// their capacity; empty ones decode as nil.
+func (v *AgentDataBinary) UnmarshalBinary(data []byte) error {
+	var r binwire.Reader
+	r.Reset(data)
+	v.Hostname = r.String()
+	v.Status = r.String()
+	v.Timestamp = int(r.Varint(0))
+	if n := r.Len(); n == 0 {
+		v.Lsns = nil
+	} else {
+		v.Lsns = v.Lsns[:0]
+		for i := 0; i < n; i++ {
+			v.Lsns = append(v.Lsns, r.String())
+		}
+	}
+	return r.Finish()
+}
+
+// SizeBinary returns the length of the binary encoding of v.
+func (v binaryKinds) SizeBinary() int {
+	n := 0
+	n += binwire.SizeString(v.S)
+	n += 1
+	n += binwire.SizeVarint(int64(v.I))
+	n += binwire.SizeVarint(int64(v.I8))
+	n += binwire.SizeVarint(int64(v.I16))
+	n += binwire.SizeVarint(int64(v.I32))
+	n += binwire.SizeVarint(int64(v.I64))
+	n += binwire.SizeUvarint(uint64(v.U))
+	n += binwire.SizeUvarint(uint64(v.U8))
+	n += binwire.SizeUvarint(uint64(v.U16))
+	n += binwire.SizeUvarint(uint64(v.U32))
+	n += binwire.SizeUvarint(uint64(v.U64))
+	n += binwire.SizeVarint(int64(v.R))
+	n += binwire.SizeUvarint(uint64(len(v.Bs)))
+	n += len(v.Bs)
+	n += binwire.SizeUvarint(uint64(len(v.I8s)))
+	for _, e := range v.I8s {
+		n += binwire.SizeVarint(int64(e))
+	}
+	n += binwire.SizeUvarint(uint64(len(v.U8s)))
+	for _, e := range v.U8s {
+		n += binwire.SizeUvarint(uint64(e))
+	}
+	n += binwire.SizeUvarint(uint64(len(v.Us)))
+	for _, e := range v.Us {
+		n += binwire.SizeUvarint(uint64(e))
+	}
+	return n
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (v binaryKinds) MarshalBinary() ([]byte, error) {
+	return v.AppendBinary(make([]byte, 0, v.SizeBinary()))
+}
+
+// AppendBinary appends the binary encoding of v to b.
+func (v binaryKinds) AppendBinary(b []byte) ([]byte, error) {
+	b = binwire.AppendString(b, v.S)
+	b = binwire.AppendBool(b, v.B)
+	b = binwire.AppendVarint(b, int64(v.I))
+	b = binwire.AppendVarint(b, int64(v.I8))
+	b = binwire.AppendVarint(b, int64(v.I16))
+	b = binwire.AppendVarint(b, int64(v.I32))
+	b = binwire.AppendVarint(b, int64(v.I64))
+	b = binwire.AppendUvarint(b, uint64(v.U))
+	b = binwire.AppendUvarint(b, uint64(v.U8))
+	b = binwire.AppendUvarint(b, uint64(v.U16))
+	b = binwire.AppendUvarint(b, uint64(v.U32))
+	b = binwire.AppendUvarint(b, uint64(v.U64))
+	b = binwire.AppendVarint(b, int64(v.R))
+	b = binwire.AppendUvarint(b, uint64(len(v.Bs)))
+	for _, e := range v.Bs {
+		b = binwire.AppendBool(b, e)
+	}
+	b = binwire.AppendUvarint(b, uint64(len(v.I8s)))
+	for _, e := range v.I8s {
+		b = binwire.AppendVarint(b, int64(e))
+	}
+	b = binwire.AppendUvarint(b, uint64(len(v.U8s)))
+	for _, e := range v.U8s {
+		b = binwire.AppendUvarint(b, uint64(e))
+	}
+	b = binwire.AppendUvarint(b, uint64(len(v.Us)))
+	for _, e := range v.Us {
+		b = binwire.AppendUvarint(b, uint64(e))
+	}
+	return b, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler. Slices reuse
+// their capacity; empty ones decode as nil.
+func (v *binaryKinds) UnmarshalBinary(data []byte) error {
+	var r binwire.Reader
+	r.Reset(data)
+	v.S = r.String()
+	v.B = r.Bool()
+	v.I = int(r.Varint(0))
+	v.I8 = int8(r.Varint(8))
+	v.I16 = int16(r.Varint(16))
+	v.I32 = int32(r.Varint(32))
+	v.I64 = r.Varint(64)
+	v.U = uint(r.Uvarint(0))
+	v.U8 = uint8(r.Uvarint(8))
+	v.U16 = uint16(r.Uvarint(16))
+	v.U32 = uint32(r.Uvarint(32))
+	v.U64 = r.Uvarint(64)
+	v.R = rune(r.Varint(32))
+	if n := r.Len(); n == 0 {
+		v.Bs = nil
+	} else {
+		v.Bs = v.Bs[:0]
+		for i := 0; i < n; i++ {
+			v.Bs = append(v.Bs, r.Bool())
+		}
+	}
+	if n := r.Len(); n == 0 {
+		v.I8s = nil
+	} else {
+		v.I8s = v.I8s[:0]
+		for i := 0; i < n; i++ {
+			v.I8s = append(v.I8s, int8(r.Varint(8)))
+		}
+	}
+	if n := r.Len(); n == 0 {
+		v.U8s = nil
+	} else {
+		v.U8s = v.U8s[:0]
+		for i := 0; i < n; i++ {
+			v.U8s = append(v.U8s, byte(r.Uvarint(8)))
+		}
+	}
+	if n := r.Len(); n == 0 {
+		v.Us = nil
+	} else {
+		v.Us = v.Us[:0]
+		for i := 0; i < n; i++ {
+			v.Us = append(v.Us, r.Uvarint(64))
+		}
+	}
+	return r.Finish()
+}
@@ -0,0 +1,108 @@
+// Code generated by jsongen -type AgentDataJSON; DO NOT EDIT.
+
+package main
+
+import "github.com/evaluate_serde_protocol/jsonwire"
+
+// MarshalJSON implements json.Marshaler.
+func (v AgentDataJSON) MarshalJSON() ([]byte, error) {
+	return v.AppendJSON(nil), nil
+}
+
+// AppendJSON appends the JSON encoding of v to b.
+func (v AgentDataJSON) AppendJSON(b []byte) []byte {
+	b = append(b, '{')
+	b = append(b, `"hostname":`...)
+	b = jsonwire.AppendString(b, v.Hostname)
+	b = append(b, `,"status":`...)
+	b = jsonwire.AppendString(b, v.Status)
+	b = append(b, `,"timestamp":`...)
+	b = jsonwire.AppendInt(b, int64(v.Timestamp))
+	b = append(b, `,"lsns":`...)
+	if v.Lsns == nil {
+		b = append(b, "null"...)
+	} else {
+		b = append(b, '[')
+		for i, e := range v.Lsns {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			b = jsonwire.AppendString(b, e)
+		}
+		b = append(b, ']')
+	}
+	return append(b, '}')
+}
+
+var jsonKeysAgentDataJSON = [...]string{
+	"hostname",
+	"status",
+	"timestamp",
+	"lsns",
+}
+
+// UnmarshalJSON implements json.Unmarshaler. Like encoding/json it matches
+// keys case-insensitively, ignores unknown keys and leaves fields that are
+// absent or null unchanged, but it stops at the first type error.
+func (v *AgentDataJSON) UnmarshalJSON(data []byte) error {
+	var l jsonwire.Lexer
+	l.Reset(data)
+	if l.Null() {
+		return l.Finish()
+	}
+	l.BeginObject()
+	for l.More('}') {
+		key := l.Key()
+		field := -1
+		switch string(key) {
+		case "hostname":
+			field = 0
+		case "status":
+			field = 1
+		case "timestamp":
+			field = 2
+		case "lsns":
+			field = 3
+		default:
+			field = jsonwire.FoldIndex(key, jsonKeysAgentDataJSON[:])
+		}
+		switch field {
+		case 0:
+			if !l.Null() {
+				v.Hostname = l.String()
+			}
+		case 1:
+			if !l.Null() {
+				v.Status = l.String()
+			}
+		case 2:
+			if !l.Null() {
+				v.Timestamp = int(l.Int(0))
+			}
+		case 3:
+			if l.Null() {
+				v.Lsns = nil
+				break
+			}
+			if v.Lsns == nil {
+				v.Lsns = []string{}
+			}
+			v.Lsns = v.Lsns[:0]
+			// As in encoding/json, a null element leaves a reused element unchanged.
+			l.BeginArray()
+			for i := 0; l.More(']'); i++ {
+				if i < cap(v.Lsns) {
+					v.Lsns = v.Lsns[:i+1]
+				} else {
+					v.Lsns = append(v.Lsns, "")
+				}
+				if !l.Null() {
+					v.Lsns[i] = l.String()
+				}
+			}
+		default:
+			l.Skip()
+		}
+	}
+	return l.Finish()
+}
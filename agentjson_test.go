@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+//go:generate go run ./cmd/jsongen -type AgentDataJSON
+
+// AgentDataJSON has the fields and json tags of AgentData, with generated
+// MarshalJSON and UnmarshalJSON methods in agentdatajson_json_test.go.
+// AgentData itself keeps none, so that BenchmarkJSONMarshal still measures
+// encoding/json's reflection.
+type AgentDataJSON struct {
+	Hostname  string   `json:"hostname"`
+	Status    string   `json:"status"`
+	Timestamp int      `json:"timestamp"`
+	Lsns      []string `json:"lsns"`
+}
+
+func generateJSONObject() *AgentDataJSON {
+	obj := AgentDataJSON(*generateObject())
+	return &obj
+}
+
+func TestGeneratedJSONMatchesEncodingJSON(t *testing.T) {
+	objs := []AgentData{
+		*generateObject(),
+		{},
+		{Lsns: []string{}},
+		{Hostname: "<a&b> \"\\\n\x01\xff", Timestamp: -1, Lsns: []string{"é", ""}},
+	}
+	for _, obj := range objs {
+		want, err := json.Marshal(obj)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := AgentDataJSON(obj).AppendJSON(nil); !bytes.Equal(got, want) {
+			t.Errorf("AppendJSON(%+v) = %s, want %s", obj, got, want)
+		}
+		if got, err := json.Marshal(AgentDataJSON(obj)); err != nil || !bytes.Equal(got, want) {
+			t.Errorf("json.Marshal(%+v) = %s, %v, want %s", obj, got, err, want)
+		}
+	}
+}
+
+func TestGeneratedJSONUnmarshal(t *testing.T) {
+	inputs := []string{
+		`{"hostname":"10.64.6.138","status":"In Progress","timestamp":1282368345,"lsns":["16/B374D848","16/B374D010"]}`,
+		" {\n\t\"HOSTNAME\" : \"h\\u00e9\\ud83d\\ude00\\/\" , \"lsns\" : [ ] } ",
+		`{"status":null,"lsns":null,"timestamp":-0}`,
+		`{"extra":{"a":[1,2.5e3,true,false,null,{}],"b":"\""},"timestamp":7,"timestamp":8}`,
+		`{"hostname":"\ud800x","lsns":["a",null]}`,
+		`null`,
+		`{}`,
+		// Malformed or mistyped input must fail in both decoders.
+		`{"hostname":"a",}`,
+		`{"hostname":"a" "status":"b"}`,
+		`{"hostname":1}`,
+		`{"timestamp":1.5}`,
+		`{"timestamp":99999999999999999999}`,
+		`{"timestamp":01}`,
+		`{"lsns":[1]}`,
+		`{"lsns":["a",]}`,
+		`{"extra":[}`,
+		`{"hostname":"a"}x`,
+		`{"hostname":"a\x"}`,
+		`{"hostname":"a`,
+		`[]`,
+		``,
+	}
+	for _, in := range inputs {
+		want := *generateObject()
+		wantErr := json.Unmarshal([]byte(in), &want)
+		got := *generateJSONObject()
+		gotErr := got.UnmarshalJSON([]byte(in))
+		if (gotErr != nil) != (wantErr != nil) {
+			t.Errorf("%s: error %v, want %v", in, gotErr, wantErr)
+			continue
+		}
+		if wantErr == nil && !reflect.DeepEqual(AgentData(got), want) {
+			t.Errorf("%s: got %+v, want %+v", in, got, want)
+		}
+	}
+}
+
+func BenchmarkGeneratedJSONMarshal(b *testing.B) {
+	obj := generateJSONObject()
+	var out []byte
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		out = obj.AppendJSON(out[:0])
+	}
+	b.ReportMetric(float64(len(out)), "bytes")
+}
+
+func BenchmarkGeneratedJSONUnmarshal(b *testing.B) {
+	out := generateJSONObject().AppendJSON(nil)
+
+	obj := &AgentDataJSON{}
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		err := obj.UnmarshalJSON(out)
+		if err != nil {
+			panic(err)
+		}
+	}
+}
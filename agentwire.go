@@ -0,0 +1,125 @@
+package main
+
+import (
+	"errors"
+	"unicode/utf8"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// A hand-written AgentProto codec on top of protowire, without the
+// reflection and table lookups of the generated code. It encodes the same
+// bytes as proto.Marshal: fields in number order, proto3 zero values
+// omitted.
+
+var errAgentProtoUTF8 = errors.New("agentwire: string field contains invalid UTF-8")
+
+const (
+	agentHostnameTag  = 1<<3 | uint64(protowire.BytesType)
+	agentStatusTag    = 2<<3 | uint64(protowire.BytesType)
+	agentTimestampTag = 3<<3 | uint64(protowire.VarintType)
+	agentLsnsTag      = 4<<3 | uint64(protowire.BytesType)
+)
+
+// sizeAgentProto returns the encoded size of m. Every tag fits in one byte.
+func sizeAgentProto(m *AgentProto) int {
+	n := 0
+	if len(m.Hostname) > 0 {
+		n += 1 + protowire.SizeBytes(len(m.Hostname))
+	}
+	if len(m.Status) > 0 {
+		n += 1 + protowire.SizeBytes(len(m.Status))
+	}
+	if m.Timestamp != 0 {
+		n += 1 + protowire.SizeVarint(uint64(m.Timestamp))
+	}
+	for _, lsn := range m.Lsns {
+		n += 1 + protowire.SizeBytes(len(lsn))
+	}
+	return n
+}
+
+// appendAgentProto appends the encoding of m to b, growing b at most once.
+func appendAgentProto(b []byte, m *AgentProto) []byte {
+	if n := sizeAgentProto(m); cap(b)-len(b) < n {
+		grown := make([]byte, len(b), len(b)+n)
+		copy(grown, b)
+		b = grown
+	}
+	if len(m.Hostname) > 0 {
+		b = append(b, byte(agentHostnameTag))
+		b = protowire.AppendString(b, m.Hostname)
+	}
+	if len(m.Status) > 0 {
+		b = append(b, byte(agentStatusTag))
+		b = protowire.AppendString(b, m.Status)
+	}
+	if m.Timestamp != 0 {
+		b = append(b, byte(agentTimestampTag))
+		b = protowire.AppendVarint(b, uint64(m.Timestamp))
+	}
+	for _, lsn := range m.Lsns {
+		b = append(b, byte(agentLsnsTag))
+		b = protowire.AppendString(b, lsn)
+	}
+	return b
+}
+
+// unmarshalAgentProto decodes b into m, reusing the capacity of m.Lsns.
+// Like proto.Unmarshal it rejects invalid UTF-8 in strings and lets the
+// last occurrence of a scalar field win, but it drops unknown fields and
+// fields of an unexpected wire type instead of keeping them.
+func unmarshalAgentProto(b []byte, m *AgentProto) error {
+	m.Hostname = ""
+	m.Status = ""
+	m.Timestamp = 0
+	m.Lsns = m.Lsns[:0]
+	m.unknownFields = nil
+	for len(b) > 0 {
+		tag, n := protowire.ConsumeVarint(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		switch tag {
+		case agentHostnameTag, agentStatusTag, agentLsnsTag:
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			if !utf8.Valid(v) {
+				return errAgentProtoUTF8
+			}
+			switch tag {
+			case agentHostnameTag:
+				m.Hostname = string(v)
+			case agentStatusTag:
+				m.Status = string(v)
+			default:
+				m.Lsns = append(m.Lsns, string(v))
+			}
+			b = b[n:]
+		case agentTimestampTag:
+			v, n := protowire.ConsumeVarint(b)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			m.Timestamp = int64(v)
+			b = b[n:]
+		default:
+			num, typ := protowire.DecodeTag(tag)
+			if num < protowire.MinValidNumber {
+				return errors.New("agentwire: invalid field number")
+			}
+			n := protowire.ConsumeFieldValue(num, typ, b)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			b = b[n:]
+		}
+	}
+	if len(m.Lsns) == 0 {
+		m.Lsns = nil
+	}
+	return nil
+}
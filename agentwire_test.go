@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestAgentWireMatchesProto(t *testing.T) {
+	msgs := []*AgentProto{
+		generateProtoBufObject(),
+		{},
+		{Timestamp: -1},
+		{Hostname: "h", Lsns: []string{"", "x"}},
+	}
+	for _, preset := range protoBufPresets {
+		msgs = append(msgs, repeatProtoBufObject(preset.repeat))
+	}
+	for _, m := range msgs {
+		want, err := proto.Marshal(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := appendAgentProto(nil, m); !bytes.Equal(got, want) {
+			t.Errorf("%v: got % x, want % x", m, got, want)
+		}
+		if n := sizeAgentProto(m); n != len(want) {
+			t.Errorf("%v: size %d, want %d", m, n, len(want))
+		}
+
+		got := &AgentProto{Lsns: []string{"stale"}}
+		if err := unmarshalAgentProto(want, got); err != nil {
+			t.Fatal(err)
+		}
+		if !proto.Equal(got, m) {
+			t.Errorf("decoded %v, want %v", got, m)
+		}
+	}
+}
+
+func TestAgentWireUnmarshalErrors(t *testing.T) {
+	out := appendAgentProto(nil, generateProtoBufObject())
+	// Unknown fields are skipped.
+	unknown := protowire.AppendTag(append([]byte(nil), out...), 9, protowire.Fixed64Type)
+	unknown = protowire.AppendFixed64(unknown, 1)
+	got := &AgentProto{}
+	if err := unmarshalAgentProto(unknown, got); err != nil || !proto.Equal(got, generateProtoBufObject()) {
+		t.Errorf("message with unknown field decoded to %v, %v", got, err)
+	}
+
+	badUTF8 := protowire.AppendTag(nil, 1, protowire.BytesType)
+	badUTF8 = protowire.AppendBytes(badUTF8, []byte{0xff})
+	for _, b := range [][]byte{out[:len(out)-1], badUTF8, {0}} {
+		if err := unmarshalAgentProto(b, got); err == nil {
+			t.Errorf("% x decoded without error", b)
+		}
+		if err := proto.Unmarshal(b, &AgentProto{}); err == nil {
+			t.Errorf("% x: proto.Unmarshal accepted it", b)
+		}
+	}
+}
+
+func BenchmarkProtoWireMarshal(b *testing.B) {
+	obj := generateProtoBufObject()
+	var out []byte
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		out = appendAgentProto(out[:0], obj)
+	}
+	b.ReportMetric(float64(len(out)), "bytes")
+}
+
+func BenchmarkProtoWireUnmarshal(b *testing.B) {
+	out := appendAgentProto(nil, generateProtoBufObject())
+
+	obj := &AgentProto{}
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		err := unmarshalAgentProto(out, obj)
+		if err != nil {
+			panic(err)
+		}
+	}
+}
@@ -0,0 +1,323 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"io/ioutil"
+)
+
+// agentAvroSchema is the Avro schema of AgentData, with the fields of
+// AgentProto in agent.proto.
+const agentAvroSchema = `{"type":"record","name":"AgentData","namespace":"agent","fields":[` +
+	`{"name":"hostname","type":"string"},` +
+	`{"name":"status","type":"string"},` +
+	`{"name":"timestamp","type":"long"},` +
+	`{"name":"lsns","type":{"type":"array","items":"string"}}]}`
+
+var errAvroCorrupt = errors.New("avro: corrupt data")
+
+// appendAvroLong appends v as an Avro long: a zig-zag encoded varint.
+func appendAvroLong(b []byte, v int64) []byte {
+	u := uint64(v<<1) ^ uint64(v>>63)
+	for u >= 0x80 {
+		b = append(b, byte(u)|0x80)
+		u >>= 7
+	}
+	return append(b, byte(u))
+}
+
+// appendAvroString appends s as an Avro string, its length followed by its
+// UTF-8 bytes.
+func appendAvroString(b []byte, s string) []byte {
+	b = appendAvroLong(b, int64(len(s)))
+	return append(b, s...)
+}
+
+// appendAvroStringArray appends ss as an Avro array of strings in a single
+// block, followed by the terminating empty block.
+func appendAvroStringArray(b []byte, ss []string) []byte {
+	if len(ss) > 0 {
+		b = appendAvroLong(b, int64(len(ss)))
+		for _, s := range ss {
+			b = appendAvroString(b, s)
+		}
+	}
+	return append(b, 0)
+}
+
+// avroReader decodes Avro binary data from a byte slice. The first error
+// is sticky and reported by err.
+type avroReader struct {
+	buf []byte
+	err error
+}
+
+func (r *avroReader) readLong() int64 {
+	var u uint64
+	for shift := uint(0); shift < 64; shift += 7 {
+		if len(r.buf) == 0 {
+			r.fail()
+			return 0
+		}
+		c := r.buf[0]
+		r.buf = r.buf[1:]
+		u |= uint64(c&0x7f) << shift
+		if c < 0x80 {
+			return int64(u>>1) ^ -int64(u&1)
+		}
+	}
+	r.fail()
+	return 0
+}
+
+func (r *avroReader) readBytes() []byte {
+	n := r.readLong()
+	if n < 0 || n > int64(len(r.buf)) {
+		r.fail()
+		return nil
+	}
+	b := r.buf[:n]
+	r.buf = r.buf[n:]
+	return b
+}
+
+func (r *avroReader) readString() string {
+	return string(r.readBytes())
+}
+
+// readStringArray decodes an Avro array of strings, appending the items to
+// dst. Blocks with a negative count, which carry their size in bytes, are
+// accepted as the specification requires.
+func (r *avroReader) readStringArray(dst []string) []string {
+	for r.err == nil {
+		n := r.readLong()
+		if n == 0 {
+			break
+		}
+		if n < 0 {
+			n = -n
+			r.readLong()
+		}
+		if n > int64(len(r.buf)) {
+			r.fail()
+			break
+		}
+		for i := int64(0); i < n; i++ {
+			dst = append(dst, r.readString())
+		}
+	}
+	return dst
+}
+
+func (r *avroReader) fail() {
+	if r.err == nil {
+		r.err = errAvroCorrupt
+	}
+	r.buf = nil
+}
+
+// avroMagic starts every Avro Object Container File.
+var avroMagic = []byte{'O', 'b', 'j', 1}
+
+const avroSyncSize = 16
+
+// avroMaxBlockSize bounds the block size a reader accepts, so a corrupt
+// block header cannot make it allocate an arbitrary amount of memory.
+const avroMaxBlockSize = 64 << 20
+
+// AvroContainerWriter writes an Avro Object Container File: a header with
+// the schema and a random sync marker, followed by blocks of datums each
+// terminated by the sync marker. Datums are buffered until a block holds
+// BlockCount of them or Flush is called. Only the null codec is supported.
+type AvroContainerWriter struct {
+	// BlockCount is the number of datums written per block.
+	BlockCount int
+
+	w     io.Writer
+	sync  [avroSyncSize]byte
+	block []byte
+	count int64
+	buf   []byte
+}
+
+// NewAvroContainerWriter writes the file header for schema to w.
+func NewAvroContainerWriter(w io.Writer, schema string) (*AvroContainerWriter, error) {
+	cw := &AvroContainerWriter{BlockCount: 1000, w: w}
+	if _, err := rand.Read(cw.sync[:]); err != nil {
+		return nil, err
+	}
+
+	header := append([]byte(nil), avroMagic...)
+	// File metadata is an Avro map of bytes, written as a single block.
+	header = appendAvroLong(header, 2)
+	header = appendAvroString(header, "avro.schema")
+	header = appendAvroString(header, schema)
+	header = appendAvroString(header, "avro.codec")
+	header = appendAvroString(header, "null")
+	header = append(header, 0)
+	header = append(header, cw.sync[:]...)
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+	return cw, nil
+}
+
+// Append adds one datum, already encoded with the writer's schema.
+func (w *AvroContainerWriter) Append(datum []byte) error {
+	w.block = append(w.block, datum...)
+	w.count++
+	if w.count >= int64(w.BlockCount) {
+		return w.Flush()
+	}
+	return nil
+}
+
+// Flush writes the buffered datums, if any, as one block.
+func (w *AvroContainerWriter) Flush() error {
+	if w.count == 0 {
+		return nil
+	}
+	w.buf = appendAvroLong(w.buf[:0], w.count)
+	w.buf = appendAvroLong(w.buf, int64(len(w.block)))
+	w.buf = append(w.buf, w.block...)
+	w.buf = append(w.buf, w.sync[:]...)
+	w.block = w.block[:0]
+	w.count = 0
+	_, err := w.w.Write(w.buf)
+	return err
+}
+
+// AvroContainerReader reads the blocks of an Avro Object Container File
+// written with the null codec.
+type AvroContainerReader struct {
+	r        *bufio.Reader
+	metadata map[string][]byte
+	sync     [avroSyncSize]byte
+	buf      []byte
+}
+
+// NewAvroContainerReader reads and checks the file header from r.
+func NewAvroContainerReader(rd io.Reader) (*AvroContainerReader, error) {
+	r := bufio.NewReader(rd)
+	cr := &AvroContainerReader{r: r, metadata: make(map[string][]byte)}
+
+	magic := make([]byte, len(avroMagic))
+	if _, err := io.ReadFull(r, magic); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(magic, avroMagic) {
+		return nil, errors.New("avro: not an object container file")
+	}
+	for {
+		n, err := readAvroLongFrom(r)
+		if err != nil {
+			return nil, err
+		}
+		if n == 0 {
+			break
+		}
+		if n < 0 {
+			n = -n
+			if _, err := readAvroLongFrom(r); err != nil {
+				return nil, err
+			}
+		}
+		for i := int64(0); i < n; i++ {
+			key, err := readAvroBytesFrom(r)
+			if err != nil {
+				return nil, err
+			}
+			value, err := readAvroBytesFrom(r)
+			if err != nil {
+				return nil, err
+			}
+			cr.metadata[string(key)] = value
+		}
+	}
+	if codec, ok := cr.metadata["avro.codec"]; ok && string(codec) != "null" {
+		return nil, errors.New("avro: unsupported codec " + string(codec))
+	}
+	if _, err := io.ReadFull(r, cr.sync[:]); err != nil {
+		return nil, err
+	}
+	return cr, nil
+}
+
+// Schema returns the writer's schema from the file header.
+func (r *AvroContainerReader) Schema() string {
+	return string(r.metadata["avro.schema"])
+}
+
+// ReadBlock returns the number of datums in the next block and their
+// encoded bytes, which are only valid until the next call. It returns
+// io.EOF after the last block.
+func (r *AvroContainerReader) ReadBlock() (int64, []byte, error) {
+	count, err := readAvroLongFrom(r.r)
+	if err != nil {
+		return 0, nil, err
+	}
+	size, err := readAvroLongFrom(r.r)
+	if err != nil {
+		return 0, nil, unexpectedEOF(err)
+	}
+	if count < 0 || size < 0 || size > avroMaxBlockSize {
+		return 0, nil, errAvroCorrupt
+	}
+	if int64(cap(r.buf)) < size+avroSyncSize {
+		r.buf = make([]byte, size+avroSyncSize)
+	}
+	buf := r.buf[:size+avroSyncSize]
+	if _, err := io.ReadFull(r.r, buf); err != nil {
+		return 0, nil, unexpectedEOF(err)
+	}
+	if !bytes.Equal(buf[size:], r.sync[:]) {
+		return 0, nil, errors.New("avro: sync marker mismatch")
+	}
+	return count, buf[:size], nil
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+func readAvroLongFrom(r *bufio.Reader) (int64, error) {
+	var u uint64
+	for shift := uint(0); shift < 64; shift += 7 {
+		c, err := r.ReadByte()
+		if err != nil {
+			if shift > 0 {
+				return 0, unexpectedEOF(err)
+			}
+			return 0, err
+		}
+		u |= uint64(c&0x7f) << shift
+		if c < 0x80 {
+			return int64(u>>1) ^ -int64(u&1), nil
+		}
+	}
+	return 0, errAvroCorrupt
+}
+
+func readAvroBytesFrom(r *bufio.Reader) ([]byte, error) {
+	n, err := readAvroLongFrom(r)
+	if err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	if n < 0 {
+		return nil, errAvroCorrupt
+	}
+	b, err := ioutil.ReadAll(io.LimitReader(r, n))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(b)) != n {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return b, nil
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+// appendAvro appends a as a datum of agentAvroSchema.
+func (a *AgentData) appendAvro(b []byte) []byte {
+	b = appendAvroString(b, a.Hostname)
+	b = appendAvroString(b, a.Status)
+	b = appendAvroLong(b, int64(a.Timestamp))
+	return appendAvroStringArray(b, a.Lsns)
+}
+
+// readAvro decodes the next datum of agentAvroSchema from r into a.
+func (a *AgentData) readAvro(r *avroReader) error {
+	a.Hostname = r.readString()
+	a.Status = r.readString()
+	a.Timestamp = int(r.readLong())
+	a.Lsns = r.readStringArray(a.Lsns[:0])
+	return r.err
+}
+
+func (a *AgentData) unmarshalAvro(data []byte) error {
+	r := &avroReader{buf: data}
+	if err := a.readAvro(r); err != nil {
+		return err
+	}
+	if len(r.buf) != 0 {
+		return errAvroCorrupt
+	}
+	return nil
+}
+
+// writeAvroContainer writes n copies of obj to an object container file.
+func writeAvroContainer(w io.Writer, obj *AgentData, n int) error {
+	cw, err := NewAvroContainerWriter(w, agentAvroSchema)
+	if err != nil {
+		return err
+	}
+	var datum []byte
+	for i := 0; i < n; i++ {
+		datum = obj.appendAvro(datum[:0])
+		if err := cw.Append(datum); err != nil {
+			return err
+		}
+	}
+	return cw.Flush()
+}
+
+// readAvroContainer calls fn with every datum of an object container file.
+func readAvroContainer(r io.Reader, fn func(*AgentData)) error {
+	cr, err := NewAvroContainerReader(r)
+	if err != nil {
+		return err
+	}
+	obj := &AgentData{}
+	for {
+		count, block, err := cr.ReadBlock()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		ar := &avroReader{buf: block}
+		for i := int64(0); i < count; i++ {
+			if err := obj.readAvro(ar); err != nil {
+				return err
+			}
+			fn(obj)
+		}
+		if len(ar.buf) != 0 {
+			return errAvroCorrupt
+		}
+	}
+}
+
+func TestAvroRoundTrip(t *testing.T) {
+	want := generateObject()
+	got := &AgentData{}
+	if err := got.unmarshalAvro(want.appendAvro(nil)); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	// Empty strings and arrays encode as a zero length, and -1 zig-zag
+	// encodes to 1.
+	out := (&AgentData{Timestamp: -1}).appendAvro(nil)
+	if !bytes.Equal(out, []byte{0, 0, 1, 0}) {
+		t.Errorf("AgentData{Timestamp: -1} encoded to %x", out)
+	}
+}
+
+func TestAvroContainer(t *testing.T) {
+	var buf bytes.Buffer
+	want := generateObject()
+	if err := writeAvroContainer(&buf, want, 2500); err != nil {
+		t.Fatal(err)
+	}
+	file := buf.Bytes()
+
+	n := 0
+	err := readAvroContainer(bytes.NewReader(file), func(got *AgentData) {
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("datum %d: got %+v, want %+v", n, got, want)
+		}
+		n++
+	})
+	if err != nil || n != 2500 {
+		t.Fatalf("read %d datums, %v; want 2500", n, err)
+	}
+
+	cr, err := NewAvroContainerReader(bytes.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cr.Schema() != agentAvroSchema {
+		t.Errorf("schema %s", cr.Schema())
+	}
+
+	file[len(file)-1] ^= 0xff
+	err = readAvroContainer(bytes.NewReader(file), func(*AgentData) {})
+	if err == nil {
+		t.Error("corrupt sync marker was not detected")
+	}
+}
+
+func TestAvroContainerCorruptBlock(t *testing.T) {
+	var buf bytes.Buffer
+	if _, err := NewAvroContainerWriter(&buf, agentAvroSchema); err != nil {
+		t.Fatal(err)
+	}
+	header := buf.Bytes()
+
+	for _, tc := range []struct {
+		name  string
+		block []byte
+		want  error
+	}{
+		{"truncated header", appendAvroLong(nil, 1), io.ErrUnexpectedEOF},
+		{"negative size", appendAvroLong(appendAvroLong(nil, 1), -1), errAvroCorrupt},
+		{"huge size", appendAvroLong(appendAvroLong(nil, 1), 1<<62), errAvroCorrupt},
+		{"oversized", appendAvroLong(appendAvroLong(nil, 1), avroMaxBlockSize+1), errAvroCorrupt},
+		{"truncated data", append(appendAvroLong(appendAvroLong(nil, 1), 10), 1, 2), io.ErrUnexpectedEOF},
+	} {
+		file := append(append([]byte(nil), header...), tc.block...)
+		cr, err := NewAvroContainerReader(bytes.NewReader(file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := cr.ReadBlock(); err != tc.want {
+			t.Errorf("%s: got %v, want %v", tc.name, err, tc.want)
+		}
+	}
+}
+
+func BenchmarkAvroMarshal(b *testing.B) {
+	obj := generateObject()
+	var out []byte
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		out = obj.appendAvro(out[:0])
+	}
+	b.ReportMetric(float64(len(out)), "bytes")
+}
+
+func BenchmarkAvroUnmarshal(b *testing.B) {
+	out := generateObject().appendAvro(nil)
+
+	obj := &AgentData{}
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		err := obj.unmarshalAvro(out)
+		if err != nil {
+			panic(err)
+		}
+	}
+}
+
+// BenchmarkAvroContainerWrite reports the cost per datum of writing an
+// object container file, including block framing and sync markers.
+func BenchmarkAvroContainerWrite(b *testing.B) {
+	obj := generateObject()
+
+	b.ResetTimer()
+	err := writeAvroContainer(ioutil.Discard, obj, b.N)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func BenchmarkAvroContainerRead(b *testing.B) {
+	var buf bytes.Buffer
+	err := writeAvroContainer(&buf, generateObject(), b.N)
+	if err != nil {
+		panic(err)
+	}
+	b.SetBytes(int64(buf.Len() / b.N))
+
+	b.ResetTimer()
+	err = readAvroContainer(&buf, func(*AgentData) {})
+	if err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,167 @@
+// Package binwire is the runtime for code generated by cmd/bingen. Values
+// are encoded back to back with no field tags: unsigned integers and
+// lengths as varints, signed integers as zig-zag varints, bools as one byte,
+// and strings and slices as their length followed by their contents.
+package binwire
+
+import (
+	"errors"
+	"strconv"
+)
+
+// ErrCorrupt is returned for data that is truncated or malformed.
+var ErrCorrupt = errors.New("binwire: corrupt data")
+
+// SizeUvarint returns the encoded length of v.
+func SizeUvarint(v uint64) int {
+	n := 1
+	for v >= 0x80 {
+		v >>= 7
+		n++
+	}
+	return n
+}
+
+// SizeVarint returns the encoded length of v.
+func SizeVarint(v int64) int {
+	return SizeUvarint(zigzag(v))
+}
+
+// SizeString returns the encoded length of s.
+func SizeString(s string) int {
+	return SizeUvarint(uint64(len(s))) + len(s)
+}
+
+func zigzag(v int64) uint64 {
+	return uint64(v<<1) ^ uint64(v>>63)
+}
+
+// AppendUvarint appends v as a varint.
+func AppendUvarint(b []byte, v uint64) []byte {
+	for v >= 0x80 {
+		b = append(b, byte(v)|0x80)
+		v >>= 7
+	}
+	return append(b, byte(v))
+}
+
+// AppendVarint appends v as a zig-zag varint.
+func AppendVarint(b []byte, v int64) []byte {
+	return AppendUvarint(b, zigzag(v))
+}
+
+// AppendBool appends v as one byte.
+func AppendBool(b []byte, v bool) []byte {
+	if v {
+		return append(b, 1)
+	}
+	return append(b, 0)
+}
+
+// AppendString appends the length of s followed by its bytes.
+func AppendString(b []byte, s string) []byte {
+	b = AppendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+// Reader decodes values from a byte slice. The first error is sticky: later
+// reads return zero values and Finish reports it.
+type Reader struct {
+	buf []byte
+	err error
+}
+
+// Reset makes the reader read data from the start.
+func (r *Reader) Reset(data []byte) {
+	r.buf = data
+	r.err = nil
+}
+
+// Finish returns the first error, or ErrCorrupt if data remains.
+func (r *Reader) Finish() error {
+	if r.err == nil && len(r.buf) != 0 {
+		r.fail(ErrCorrupt)
+	}
+	return r.err
+}
+
+func (r *Reader) fail(err error) {
+	if r.err == nil {
+		r.err = err
+	}
+	r.buf = nil
+}
+
+// Uvarint reads an unsigned integer that fits in bitSize bits, where 0
+// means uint.
+func (r *Reader) Uvarint(bitSize int) uint64 {
+	var v uint64
+	for shift := uint(0); shift < 64; shift += 7 {
+		if len(r.buf) == 0 {
+			r.fail(ErrCorrupt)
+			return 0
+		}
+		c := r.buf[0]
+		r.buf = r.buf[1:]
+		if shift == 63 && c > 1 {
+			break
+		}
+		v |= uint64(c&0x7f) << shift
+		if c < 0x80 {
+			if bitSize == 0 {
+				bitSize = strconv.IntSize
+			}
+			if bitSize < 64 && v>>uint(bitSize) != 0 {
+				break
+			}
+			return v
+		}
+	}
+	r.fail(ErrCorrupt)
+	return 0
+}
+
+// Varint reads a signed integer that fits in bitSize bits, where 0 means
+// int.
+func (r *Reader) Varint(bitSize int) int64 {
+	if bitSize == 0 {
+		bitSize = strconv.IntSize
+	}
+	u := r.Uvarint(bitSize)
+	return int64(u>>1) ^ -int64(u&1)
+}
+
+// Bool reads a bool written by AppendBool.
+func (r *Reader) Bool() bool {
+	if len(r.buf) == 0 || r.buf[0] > 1 {
+		r.fail(ErrCorrupt)
+		return false
+	}
+	v := r.buf[0] == 1
+	r.buf = r.buf[1:]
+	return v
+}
+
+// String reads a string written by AppendString.
+func (r *Reader) String() string {
+	n := r.Uvarint(64)
+	if n > uint64(len(r.buf)) {
+		r.fail(ErrCorrupt)
+		return ""
+	}
+	s := string(r.buf[:n])
+	r.buf = r.buf[n:]
+	return s
+}
+
+// Len reads the length of a slice. Every element takes at least one byte,
+// so a length beyond the remaining data is rejected before anything is
+// allocated for it.
+func (r *Reader) Len() int {
+	n := r.Uvarint(64)
+	if n > uint64(len(r.buf)) {
+		r.fail(ErrCorrupt)
+		return 0
+	}
+	return int(n)
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"strconv"
+)
+
+// BSON element types, from the specification at bsonspec.org.
+const (
+	bsonDouble     = 0x01
+	bsonString     = 0x02
+	bsonDocument   = 0x03
+	bsonArray      = 0x04
+	bsonBinary     = 0x05
+	bsonObjectID   = 0x07
+	bsonBool       = 0x08
+	bsonDateTime   = 0x09
+	bsonNull       = 0x0a
+	bsonInt32      = 0x10
+	bsonTimestamp  = 0x11
+	bsonInt64      = 0x12
+	bsonDecimal128 = 0x13
+)
+
+var errBSONCorrupt = errors.New("bson: corrupt document")
+
+// bsonDocumentBegin reserves the int32 length prefix of a document and
+// returns the offset to pass to bsonDocumentEnd.
+func bsonDocumentBegin(b []byte) ([]byte, int) {
+	return append(b, 0, 0, 0, 0), len(b)
+}
+
+// bsonDocumentEnd terminates the document started at start and fills in its
+// length, which counts the prefix and the terminating zero.
+func bsonDocumentEnd(b []byte, start int) []byte {
+	b = append(b, 0)
+	binary.LittleEndian.PutUint32(b[start:], uint32(len(b)-start))
+	return b
+}
+
+func appendBSONKey(b []byte, typ byte, key string) []byte {
+	b = append(b, typ)
+	b = append(b, key...)
+	return append(b, 0)
+}
+
+// appendBSONString appends a string element: its length including the
+// terminating zero, followed by its UTF-8 bytes and the zero.
+func appendBSONString(b []byte, key, s string) []byte {
+	b = appendBSONKey(b, bsonString, key)
+	b = appendUint32LE(b, uint32(len(s)+1))
+	b = append(b, s...)
+	return append(b, 0)
+}
+
+func appendBSONInt64(b []byte, key string, v int64) []byte {
+	b = appendBSONKey(b, bsonInt64, key)
+	return appendUint64LE(b, uint64(v))
+}
+
+// appendBSONStringArray appends an array element, a document whose keys are
+// the indexes "0", "1", ... of ss.
+func appendBSONStringArray(b []byte, key string, ss []string) []byte {
+	b = appendBSONKey(b, bsonArray, key)
+	b, start := bsonDocumentBegin(b)
+	var index [20]byte
+	for i, s := range ss {
+		b = appendBSONString(b, string(strconv.AppendInt(index[:0], int64(i), 10)), s)
+	}
+	return bsonDocumentEnd(b, start)
+}
+
+func appendUint32LE(b []byte, v uint32) []byte {
+	return append(b, byte(v), byte(v>>8), byte(v>>16), byte(v>>24))
+}
+
+func appendUint64LE(b []byte, v uint64) []byte {
+	return append(b, byte(v), byte(v>>8), byte(v>>16), byte(v>>24),
+		byte(v>>32), byte(v>>40), byte(v>>48), byte(v>>56))
+}
+
+// bsonReader iterates over the elements of one BSON document. The first
+// error is sticky and reported by err.
+type bsonReader struct {
+	buf []byte
+	e   error
+}
+
+// newBSONReader checks that data holds exactly one document, whose length
+// prefix covers all of data and which ends with a zero byte.
+func newBSONReader(data []byte) *bsonReader {
+	r := &bsonReader{}
+	if len(data) < 5 || int(binary.LittleEndian.Uint32(data)) != len(data) || data[len(data)-1] != 0 {
+		r.fail()
+		return r
+	}
+	r.buf = data[4 : len(data)-1]
+	return r
+}
+
+// next returns the type and key of the next element, or ok false after the
+// last element or on error.
+func (r *bsonReader) next() (typ byte, key string, ok bool) {
+	if r.e != nil || len(r.buf) == 0 {
+		return 0, "", false
+	}
+	typ = r.buf[0]
+	end := 1
+	for end < len(r.buf) && r.buf[end] != 0 {
+		end++
+	}
+	if end == len(r.buf) {
+		r.fail()
+		return 0, "", false
+	}
+	key = string(r.buf[1:end])
+	r.buf = r.buf[end+1:]
+	return typ, key, true
+}
+
+func (r *bsonReader) bytes(n int) []byte {
+	if r.e != nil || n < 0 || n > len(r.buf) {
+		r.fail()
+		return nil
+	}
+	b := r.buf[:n]
+	r.buf = r.buf[n:]
+	return b
+}
+
+func (r *bsonReader) readInt32() int32 {
+	b := r.bytes(4)
+	if b == nil {
+		return 0
+	}
+	return int32(binary.LittleEndian.Uint32(b))
+}
+
+func (r *bsonReader) readInt64() int64 {
+	b := r.bytes(8)
+	if b == nil {
+		return 0
+	}
+	return int64(binary.LittleEndian.Uint64(b))
+}
+
+func (r *bsonReader) readString() string {
+	n := r.readInt32()
+	if n < 1 {
+		r.fail()
+		return ""
+	}
+	b := r.bytes(int(n))
+	if b == nil || b[n-1] != 0 {
+		r.fail()
+		return ""
+	}
+	return string(b[:n-1])
+}
+
+// readDocument returns a reader over an embedded document or array.
+func (r *bsonReader) readDocument() *bsonReader {
+	if len(r.buf) < 4 {
+		r.fail()
+		return r
+	}
+	d := newBSONReader(r.bytes(int(binary.LittleEndian.Uint32(r.buf))))
+	if d.e != nil {
+		r.fail()
+	}
+	return d
+}
+
+// readStringArray appends the items of an array of strings to dst.
+func (r *bsonReader) readStringArray(dst []string) []string {
+	a := r.readDocument()
+	for {
+		typ, _, ok := a.next()
+		if !ok {
+			break
+		}
+		if typ != bsonString {
+			a.fail()
+			break
+		}
+		dst = append(dst, a.readString())
+	}
+	if a.e != nil {
+		r.fail()
+	}
+	return dst
+}
+
+// skip discards the value of an element of type typ.
+func (r *bsonReader) skip(typ byte) {
+	switch typ {
+	case bsonNull:
+	case bsonBool:
+		r.bytes(1)
+	case bsonInt32:
+		r.bytes(4)
+	case bsonDouble, bsonDateTime, bsonTimestamp, bsonInt64:
+		r.bytes(8)
+	case bsonObjectID:
+		r.bytes(12)
+	case bsonDecimal128:
+		r.bytes(16)
+	case bsonString:
+		r.readString()
+	case bsonBinary:
+		// The length excludes the subtype byte that precedes the data.
+		if n := r.readInt32(); n < 0 {
+			r.fail()
+		} else {
+			r.bytes(int(n) + 1)
+		}
+	case bsonDocument, bsonArray:
+		r.readDocument()
+	default:
+		r.fail()
+	}
+}
+
+func (r *bsonReader) fail() {
+	if r.e == nil {
+		r.e = errBSONCorrupt
+	}
+	r.buf = nil
+}
+
+func (r *bsonReader) err() error {
+	return r.e
+}
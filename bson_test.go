@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+// appendBSON appends a as a BSON document keyed by its json tags.
+func (a *AgentData) appendBSON(b []byte) []byte {
+	b, start := bsonDocumentBegin(b)
+	b = appendBSONString(b, "hostname", a.Hostname)
+	b = appendBSONString(b, "status", a.Status)
+	b = appendBSONInt64(b, "timestamp", int64(a.Timestamp))
+	b = appendBSONStringArray(b, "lsns", a.Lsns)
+	return bsonDocumentEnd(b, start)
+}
+
+// unmarshalBSON decodes a document written by appendBSON into a, skipping
+// unknown elements.
+func (a *AgentData) unmarshalBSON(data []byte) error {
+	*a = AgentData{Lsns: a.Lsns[:0]}
+	r := newBSONReader(data)
+	for {
+		typ, key, ok := r.next()
+		if !ok {
+			break
+		}
+		switch {
+		case key == "hostname" && typ == bsonString:
+			a.Hostname = r.readString()
+		case key == "status" && typ == bsonString:
+			a.Status = r.readString()
+		case key == "timestamp" && typ == bsonInt64:
+			a.Timestamp = int(r.readInt64())
+		case key == "timestamp" && typ == bsonInt32:
+			a.Timestamp = int(r.readInt32())
+		case key == "lsns" && typ == bsonArray:
+			a.Lsns = r.readStringArray(a.Lsns)
+		default:
+			r.skip(typ)
+		}
+	}
+	return r.err()
+}
+
+func TestBSONRoundTrip(t *testing.T) {
+	want := generateObject()
+	out := want.appendBSON(nil)
+	got := &AgentData{}
+	if err := got.unmarshalBSON(out); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	for _, corrupt := range [][]byte{
+		out[:len(out)-1],
+		append(out[:len(out):len(out)], 0),
+		nil,
+	} {
+		if err := got.unmarshalBSON(corrupt); err == nil {
+			t.Errorf("document of %d bytes decoded without error", len(corrupt))
+		}
+	}
+}
+
+func TestBSONEncoding(t *testing.T) {
+	out := (&AgentData{Hostname: "h", Timestamp: -1, Lsns: []string{"x"}}).appendBSON(nil)
+	want := []byte{
+		0x49, 0, 0, 0, // document length
+		0x02, 'h', 'o', 's', 't', 'n', 'a', 'm', 'e', 0, 2, 0, 0, 0, 'h', 0,
+		0x02, 's', 't', 'a', 't', 'u', 's', 0, 1, 0, 0, 0, 0,
+		0x12, 't', 'i', 'm', 'e', 's', 't', 'a', 'm', 'p', 0,
+		0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+		0x04, 'l', 's', 'n', 's', 0,
+		0x0e, 0, 0, 0, 0x02, '0', 0, 2, 0, 0, 0, 'x', 0, 0, // array document
+		0, // end of document
+	}
+	if !bytes.Equal(out, want) {
+		t.Errorf("got % x, want % x", out, want)
+	}
+}
+
+// TestBSONSkip decodes a document with elements AgentData does not know,
+// as written by a document store that adds its own fields.
+func TestBSONSkip(t *testing.T) {
+	b, start := bsonDocumentBegin(nil)
+	b = appendBSONKey(b, bsonObjectID, "_id")
+	b = append(b, make([]byte, 12)...)
+	b = appendBSONString(b, "hostname", "db1")
+	b = appendBSONKey(b, bsonDocument, "meta")
+	b, meta := bsonDocumentBegin(b)
+	b = appendBSONKey(b, bsonBool, "primary")
+	b = append(b, 1)
+	b = appendBSONKey(b, bsonNull, "region")
+	b = bsonDocumentEnd(b, meta)
+	b = appendBSONKey(b, bsonBinary, "token")
+	b = appendUint32LE(b, 3)
+	b = append(b, 0, 'a', 'b', 'c')
+	b = appendBSONKey(b, bsonInt32, "timestamp")
+	b = appendUint32LE(b, 42)
+	b = bsonDocumentEnd(b, start)
+
+	got := &AgentData{}
+	if err := got.unmarshalBSON(b); err != nil {
+		t.Fatal(err)
+	}
+	if got.Hostname != "db1" || got.Timestamp != 42 {
+		t.Errorf("got %+v", got)
+	}
+
+	// With a binary length of -1 nothing would be skipped, not even the
+	// subtype byte, and the element after the length would be read as the
+	// next one.
+	for _, n := range []int32{-1, -2, 1 << 30} {
+		b, start := bsonDocumentBegin(nil)
+		b = appendBSONKey(b, bsonBinary, "token")
+		b = appendUint32LE(b, uint32(n))
+		b = appendBSONKey(b, bsonInt32, "timestamp")
+		b = appendUint32LE(b, 42)
+		b = bsonDocumentEnd(b, start)
+		if err := got.unmarshalBSON(b); err == nil {
+			t.Errorf("binary length %d decoded without error", n)
+		}
+	}
+}
+
+func BenchmarkBSONMarshal(b *testing.B) {
+	obj := generateObject()
+	var out []byte
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		out = obj.appendBSON(out[:0])
+	}
+	b.ReportMetric(float64(len(out)), "bytes")
+}
+
+func BenchmarkBSONUnmarshal(b *testing.B) {
+	out := generateObject().appendBSON(nil)
+
+	obj := &AgentData{}
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		err := obj.unmarshalBSON(out)
+		if err != nil {
+			panic(err)
+		}
+	}
+}
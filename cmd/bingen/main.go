@@ -0,0 +1,314 @@
+// Bingen generates MarshalBinary, AppendBinary and UnmarshalBinary methods
+// for struct types, encoding them without reflection in the compact layout
+// of the binwire package, and a round-trip test for each type.
+//
+// Fields are encoded in declaration order with no tags or names, so the
+// encoding is meant for Go programs built from the same definitions;
+// reordering, adding or removing fields changes it. Unexported fields and
+// fields tagged `bin:"-"` are skipped. Fields may be strings, bools,
+// integers, or slices of those, []byte included, as read by the gostruct
+// package.
+//
+// Usage, from a go:generate directive in the file that declares the types:
+//
+//	//go:generate go run ./cmd/bingen -type T[,T...] [-output file] [-test file]
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/evaluate_serde_protocol/internal/gostruct"
+)
+
+const defaultRuntime = "github.com/evaluate_serde_protocol/binwire"
+
+var (
+	typeNames = flag.String("type", "", "comma-separated list of struct type names; must be set")
+	output    = flag.String("output", "", "output file name; default <type>_binary.go in the input's directory")
+	testFile  = flag.String("test", "", "test file name; default <type>_binary_roundtrip_test.go in the input's directory")
+	runtime   = flag.String("runtime", defaultRuntime, "import path of the binwire package")
+)
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("bingen: ")
+	flag.Parse()
+	input := flag.Arg(0)
+	if input == "" {
+		input = os.Getenv("GOFILE")
+	}
+	if *typeNames == "" || input == "" {
+		log.Fatal("usage: bingen -type T[,T...] [-output file] [-test file] [file.go]")
+	}
+	types := strings.Split(*typeNames, ",")
+
+	src, test, err := generate(input, types, *runtime)
+	if err != nil {
+		log.Fatal(err)
+	}
+	base := filepath.Join(filepath.Dir(input), strings.ToLower(types[0]))
+	out := *output
+	if out == "" {
+		out = base + "_binary.go"
+		if strings.HasSuffix(input, "_test.go") {
+			out = base + "_binary_test.go"
+		}
+	}
+	if *testFile == "" {
+		*testFile = base + "_binary_roundtrip_test.go"
+	}
+	if err := ioutil.WriteFile(out, src, 0644); err != nil {
+		log.Fatal(err)
+	}
+	if err := ioutil.WriteFile(*testFile, test, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// kind is the Go type of a field, or of a slice field's elements. There is
+// one for each of gostruct.Types.
+type kind struct {
+	name    string // Go type name, e.g. "int32"
+	size    string // binwire function returning the encoded size
+	encode  string // binwire function that appends it
+	decode  string // Reader call that reads it
+	convert string // conversion of the value passed to size and encode
+	min     string // sample values for the generated test
+	max     string
+}
+
+var kinds = map[string]kind{
+	"string": {encode: "AppendString", decode: "r.String()", size: "SizeString",
+		min: `"\x00\xff"`, max: `"é, and then some"`},
+	"bool": {encode: "AppendBool", decode: "r.Bool()", min: "false", max: "true"},
+}
+
+func init() {
+	for _, bits := range []int{0, 8, 16, 32, 64} {
+		suffix := ""
+		if bits != 0 {
+			suffix = strconv.Itoa(bits)
+		}
+		// int and uint are only sampled in their 32-bit range, which
+		// holds on every platform.
+		sample := bits
+		if sample == 0 {
+			sample = 32
+		}
+		kinds["int"+suffix] = kind{encode: "AppendVarint", decode: "r.Varint(" + strconv.Itoa(bits) + ")",
+			size: "SizeVarint", convert: "int64",
+			min: fmt.Sprintf("-1 << %d", sample-1), max: fmt.Sprintf("1<<%d - 1", sample-1)}
+		kinds["uint"+suffix] = kind{encode: "AppendUvarint", decode: "r.Uvarint(" + strconv.Itoa(bits) + ")",
+			size: "SizeUvarint", convert: "uint64",
+			min: "0", max: fmt.Sprintf("1<<%d - 1", sample)}
+	}
+	kinds["byte"] = kinds["uint8"]
+	kinds["rune"] = kinds["int32"]
+	for name, k := range kinds {
+		k.name = name
+		kinds[name] = k
+	}
+}
+
+type field struct {
+	name  string
+	kind  kind
+	slice bool
+}
+
+// generate returns the formatted source of the methods for types, which
+// must be struct types declared in filename, and of their tests.
+func generate(filename string, types []string, runtime string) (src, test []byte, err error) {
+	file, err := gostruct.ParseFile(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	header := fmt.Sprintf("// Code generated by bingen -type %s; DO NOT EDIT.\n\npackage %s\n",
+		strings.Join(types, ","), file.Package)
+	var buf, tbuf bytes.Buffer
+	buf.WriteString(header)
+	fmt.Fprintf(&buf, "\nimport %q\n", runtime)
+	tbuf.WriteString(header)
+	tbuf.WriteString("\nimport (\n\"reflect\"\n\"testing\"\n)\n")
+	for _, name := range types {
+		fields, err := structFields(file, name)
+		if err != nil {
+			return nil, nil, err
+		}
+		writeMethods(&buf, name, fields)
+		writeTest(&tbuf, name, fields)
+	}
+	if src, err = format.Source(buf.Bytes()); err != nil {
+		return nil, nil, err
+	}
+	if test, err = format.Source(tbuf.Bytes()); err != nil {
+		return nil, nil, err
+	}
+	return src, test, nil
+}
+
+// structFields returns the fields of the struct type name to encode, in
+// declaration order.
+func structFields(file *gostruct.File, name string) ([]field, error) {
+	sfs, err := file.Fields(name, "bin")
+	if err != nil {
+		return nil, err
+	}
+	fields := make([]field, len(sfs))
+	for i, sf := range sfs {
+		fields[i] = field{name: sf.Name, kind: kinds[sf.Type], slice: sf.Slice}
+	}
+	return fields, nil
+}
+
+func convert(k kind, value string) string {
+	if k.convert == "" {
+		return value
+	}
+	return k.convert + "(" + value + ")"
+}
+
+// decode returns the expression reading a value of kind k, converted to
+// the field's type.
+func decode(k kind) string {
+	switch k.name {
+	case "string", "bool", "int64", "uint64":
+		return k.decode
+	}
+	return k.name + "(" + k.decode + ")"
+}
+
+// sizeOf returns the expression for the encoded size of value.
+func sizeOf(k kind, value string) string {
+	if k.size == "" {
+		return "1"
+	}
+	return "binwire." + k.size + "(" + convert(k, value) + ")"
+}
+
+func writeMethods(buf *bytes.Buffer, name string, fields []field) {
+	fmt.Fprintf(buf, `
+// SizeBinary returns the length of the binary encoding of v.
+func (v %s) SizeBinary() int {
+	n := 0
+`, name)
+	for _, f := range fields {
+		value := "v." + f.name
+		if !f.slice {
+			fmt.Fprintf(buf, "n += %s\n", sizeOf(f.kind, value))
+			continue
+		}
+		fmt.Fprintf(buf, "n += binwire.SizeUvarint(uint64(len(%s)))\n", value)
+		if f.kind.size == "" {
+			fmt.Fprintf(buf, "n += len(%s)\n", value)
+			continue
+		}
+		fmt.Fprintf(buf, "for _, e := range %s {\nn += %s\n}\n", value, sizeOf(f.kind, "e"))
+	}
+	fmt.Fprintf(buf, `return n
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (v %[1]s) MarshalBinary() ([]byte, error) {
+	return v.AppendBinary(make([]byte, 0, v.SizeBinary()))
+}
+
+// AppendBinary appends the binary encoding of v to b.
+func (v %[1]s) AppendBinary(b []byte) ([]byte, error) {
+`, name)
+	for _, f := range fields {
+		value := "v." + f.name
+		if f.slice {
+			fmt.Fprintf(buf, "b = binwire.AppendUvarint(b, uint64(len(%s)))\n", value)
+			fmt.Fprintf(buf, "for _, e := range %s {\nb = binwire.%s(b, %s)\n}\n", value, f.kind.encode, convert(f.kind, "e"))
+		} else {
+			fmt.Fprintf(buf, "b = binwire.%s(b, %s)\n", f.kind.encode, convert(f.kind, value))
+		}
+	}
+	fmt.Fprintf(buf, `return b, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler. Slices reuse
+// their capacity; empty ones decode as nil.
+func (v *%s) UnmarshalBinary(data []byte) error {
+	var r binwire.Reader
+	r.Reset(data)
+`, name)
+	for _, f := range fields {
+		value := "v." + f.name
+		if f.slice {
+			fmt.Fprintf(buf, `if n := r.Len(); n == 0 {
+	%[1]s = nil
+} else {
+	%[1]s = %[1]s[:0]
+	for i := 0; i < n; i++ {
+		%[1]s = append(%[1]s, %[2]s)
+	}
+}
+`, value, decode(f.kind))
+		} else {
+			fmt.Fprintf(buf, "%s = %s\n", value, decode(f.kind))
+		}
+	}
+	fmt.Fprintf(buf, "return r.Finish()\n}\n")
+}
+
+// writeTest writes a test that round-trips the zero value and values with
+// the extremes of every field, and checks that every truncation of their
+// encoding and trailing data are rejected.
+func writeTest(buf *bytes.Buffer, name string, fields []field) {
+	fmt.Fprintf(buf, "\nfunc Test%sBinaryRoundTrip(t *testing.T) {\nfor _, want := range []%s{\n{},\n",
+		strings.ToUpper(name[:1])+name[1:], name)
+	for _, sample := range []func(kind) string{
+		func(k kind) string { return k.min },
+		func(k kind) string { return k.max },
+	} {
+		buf.WriteString("{\n")
+		for _, f := range fields {
+			v := sample(f.kind)
+			if f.slice {
+				v = fmt.Sprintf("[]%s{%s, %s}", f.kind.name, v, f.kind.max)
+			} else if f.kind.convert != "" {
+				v = f.kind.name + "(" + v + ")"
+			}
+			fmt.Fprintf(buf, "%s: %s,\n", f.name, v)
+		}
+		buf.WriteString("},\n")
+	}
+	fmt.Fprintf(buf, `} {
+		data, err := want.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(data) != want.SizeBinary() {
+			t.Errorf("%%+v: encoded %%d bytes, SizeBinary %%d", want, len(data), want.SizeBinary())
+		}
+		var got %s
+		if err := got.UnmarshalBinary(data); err != nil {
+			t.Fatalf("%%+v: %%v", want, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %%+v, want %%+v", got, want)
+		}
+		for n := 0; n < len(data); n++ {
+			if err := got.UnmarshalBinary(data[:n]); err == nil {
+				t.Errorf("%%+v: %%d of %%d bytes decoded without error", want, n, len(data))
+			}
+		}
+		if err := got.UnmarshalBinary(append(data, 0)); err == nil {
+			t.Errorf("%%+v: trailing data decoded without error", want)
+		}
+	}
+}
+`, name)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/evaluate_serde_protocol/internal/gostruct"
+)
+
+// TestGeneratedUpToDate checks that the checked-in code and tests for the
+// types in agentbinary_test.go match what bingen generates now.
+func TestGeneratedUpToDate(t *testing.T) {
+	root := filepath.Join("..", "..")
+	src, test, err := generate(filepath.Join(root, "agentbinary_test.go"), []string{"AgentDataBinary", "binaryKinds"}, defaultRuntime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for file, got := range map[string][]byte{
+		"agentdatabinary_binary_test.go":           src,
+		"agentdatabinary_binary_roundtrip_test.go": test,
+	} {
+		want, err := ioutil.ReadFile(filepath.Join(root, file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s is stale; run go generate", file)
+		}
+	}
+}
+
+// TestKinds checks that every type gostruct accepts has a kind.
+func TestKinds(t *testing.T) {
+	for name := range gostruct.Types {
+		if k, ok := kinds[name]; !ok || k.name != name {
+			t.Errorf("no kind for %s", name)
+		}
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	for _, tc := range []struct {
+		src, err string
+	}{
+		{"type T struct{ F float64 }", "unsupported type"},
+		{"type T struct{ F map[string]string }", "unsupported type"},
+		{"type T struct{ F [4]byte }", "unsupported type"},
+		{"type T struct{ U }; type U struct{}", "embedded"},
+		{"type U struct{}", "no struct type T"},
+	} {
+		dir, err := ioutil.TempDir("", "bingen")
+		if err != nil {
+			t.Fatal(err)
+		}
+		file := filepath.Join(dir, "t.go")
+		if err := ioutil.WriteFile(file, []byte("package p\n"+tc.src+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		_, _, err = generate(file, []string{"T"}, defaultRuntime)
+		os.RemoveAll(dir)
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%s: error %v, want %q", tc.src, err, tc.err)
+		}
+	}
+}
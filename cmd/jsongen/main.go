@@ -0,0 +1,311 @@
+// Jsongen generates MarshalJSON, AppendJSON and UnmarshalJSON methods for
+// struct types, so that they encode and decode JSON without reflection. The
+// generated code produces the same bytes as encoding/json and honors json
+// field tags, including "-" and omitempty; it relies on the jsonwire
+// package at run time.
+//
+// Fields may be strings, bools, integers, or slices of those, as read by
+// the gostruct package; []byte, which encoding/json encodes as base64, and
+// the ",string" tag option are rejected.
+//
+// Usage, from a go:generate directive in the file that declares the types:
+//
+//	//go:generate go run ./cmd/jsongen -type T[,T...] [-output file]
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/evaluate_serde_protocol/internal/gostruct"
+	"github.com/evaluate_serde_protocol/jsonwire"
+)
+
+const defaultRuntime = "github.com/evaluate_serde_protocol/jsonwire"
+
+var (
+	typeNames = flag.String("type", "", "comma-separated list of struct type names; must be set")
+	output    = flag.String("output", "", "output file name; default <type>_json.go in the input's directory")
+	runtime   = flag.String("runtime", defaultRuntime, "import path of the jsonwire package")
+)
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("jsongen: ")
+	flag.Parse()
+	input := flag.Arg(0)
+	if input == "" {
+		input = os.Getenv("GOFILE")
+	}
+	if *typeNames == "" || input == "" {
+		log.Fatal("usage: jsongen -type T[,T...] [-output file] [file.go]")
+	}
+	types := strings.Split(*typeNames, ",")
+
+	src, err := generate(input, types, *runtime)
+	if err != nil {
+		log.Fatal(err)
+	}
+	out := *output
+	if out == "" {
+		suffix := "_json.go"
+		if strings.HasSuffix(input, "_test.go") {
+			suffix = "_json_test.go"
+		}
+		out = filepath.Join(filepath.Dir(input), strings.ToLower(types[0])+suffix)
+	}
+	if err := ioutil.WriteFile(out, src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// kind is the Go type of a field, or of a slice field's elements. There is
+// one for each of gostruct.Types.
+type kind struct {
+	name    string // Go type name, e.g. "int32"
+	decode  string // Lexer call that reads it
+	encode  string // jsonwire function that appends it
+	convert string // conversion of the value passed to encode, if any
+	zero    string // zero value, for omitempty
+}
+
+var kinds = map[string]kind{
+	"string": {decode: "l.String()", encode: "AppendString", zero: `""`},
+	"bool":   {decode: "l.Bool()", encode: "AppendBool", zero: "false"},
+}
+
+func init() {
+	for _, bits := range []string{"", "8", "16", "32", "64"} {
+		size := bits
+		if size == "" {
+			size = "0"
+		}
+		kinds["int"+bits] = kind{decode: "l.Int(" + size + ")", encode: "AppendInt", convert: "int64", zero: "0"}
+		kinds["uint"+bits] = kind{decode: "l.Uint(" + size + ")", encode: "AppendUint", convert: "uint64", zero: "0"}
+	}
+	kinds["byte"] = kinds["uint8"]
+	kinds["rune"] = kinds["int32"]
+	for name, k := range kinds {
+		k.name = name
+		kinds[name] = k
+	}
+}
+
+type field struct {
+	goName    string
+	key       string
+	kind      kind
+	slice     bool
+	omitEmpty bool
+}
+
+// generate returns the formatted source of the methods for types, which
+// must be struct types declared in filename.
+func generate(filename string, types []string, runtime string) ([]byte, error) {
+	file, err := gostruct.ParseFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by jsongen -type %s; DO NOT EDIT.\n\n", strings.Join(types, ","))
+	fmt.Fprintf(&buf, "package %s\n\nimport %q\n", file.Package, runtime)
+	for _, name := range types {
+		fields, err := structFields(file, name)
+		if err != nil {
+			return nil, err
+		}
+		writeMarshal(&buf, name, fields)
+		writeUnmarshal(&buf, name, fields)
+	}
+	return format.Source(buf.Bytes())
+}
+
+// structFields returns the fields of the struct type name that
+// encoding/json would encode, with the keys from their json tags.
+func structFields(file *gostruct.File, name string) ([]field, error) {
+	sfs, err := file.Fields(name, "json")
+	if err != nil {
+		return nil, err
+	}
+	var fields []field
+	keys := make(map[string]bool)
+	for _, sf := range sfs {
+		if sf.Bytes() {
+			// encoding/json encodes []byte as base64.
+			return nil, fmt.Errorf("%s: field %s.%s has an unsupported type []%s", sf.Pos, name, sf.Name, sf.Type)
+		}
+		key, options := gostruct.SplitTag(sf.Tag)
+		omitEmpty := false
+		for _, option := range options {
+			switch option {
+			case "":
+			case "omitempty":
+				omitEmpty = true
+			default:
+				return nil, fmt.Errorf("%s: tag option %q is not supported", sf.Pos, option)
+			}
+		}
+		if key == "" {
+			key = sf.Name
+		}
+		if keys[key] {
+			return nil, fmt.Errorf("%s: duplicate JSON key %q", sf.Pos, key)
+		}
+		keys[key] = true
+		fields = append(fields, field{goName: sf.Name, key: key, kind: kinds[sf.Type], slice: sf.Slice, omitEmpty: omitEmpty})
+	}
+	return fields, nil
+}
+
+func writeMarshal(buf *bytes.Buffer, name string, fields []field) {
+	fmt.Fprintf(buf, `
+// MarshalJSON implements json.Marshaler.
+func (v %[1]s) MarshalJSON() ([]byte, error) {
+	return v.AppendJSON(nil), nil
+}
+
+// AppendJSON appends the JSON encoding of v to b.
+func (v %[1]s) AppendJSON(b []byte) []byte {
+	b = append(b, '{')
+`, name)
+	// written reports whether an earlier member is always present, so
+	// that a comma is known to be needed; maybe whether one can be.
+	written, maybe := false, false
+	for _, f := range fields {
+		value := "v." + f.goName
+		if f.omitEmpty {
+			if f.slice {
+				fmt.Fprintf(buf, "if len(%s) != 0 {\n", value)
+			} else if f.kind.name == "bool" {
+				fmt.Fprintf(buf, "if %s {\n", value)
+			} else {
+				fmt.Fprintf(buf, "if %s != %s {\n", value, f.kind.zero)
+			}
+		}
+		key := string(jsonwire.AppendString(nil, f.key)) + ":"
+		switch {
+		case written:
+			key = "," + key
+		case maybe:
+			fmt.Fprintf(buf, "b = jsonwire.AppendComma(b)\n")
+		}
+		fmt.Fprintf(buf, "b = append(b, %s...)\n", quote(key))
+		if f.slice {
+			fmt.Fprintf(buf, `if %[1]s == nil {
+	b = append(b, "null"...)
+} else {
+	b = append(b, '[')
+	for i, e := range %[1]s {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = jsonwire.%[2]s(b, %[3]s)
+	}
+	b = append(b, ']')
+}
+`, value, f.kind.encode, convert(f.kind, "e"))
+		} else {
+			fmt.Fprintf(buf, "b = jsonwire.%s(b, %s)\n", f.kind.encode, convert(f.kind, value))
+		}
+		if f.omitEmpty {
+			fmt.Fprintf(buf, "}\n")
+		} else {
+			written = true
+		}
+		maybe = true
+	}
+	fmt.Fprintf(buf, "return append(b, '}')\n}\n")
+}
+
+// quote returns s as a Go string literal, raw if possible.
+func quote(s string) string {
+	if strconv.CanBackquote(s) {
+		return "`" + s + "`"
+	}
+	return strconv.Quote(s)
+}
+
+func convert(k kind, value string) string {
+	if k.convert == "" {
+		return value
+	}
+	return k.convert + "(" + value + ")"
+}
+
+// decode returns the expression reading a value of kind k, converted to
+// the field's type.
+func decode(k kind) string {
+	switch k.name {
+	case "string", "bool", "int64", "uint64":
+		return k.decode
+	}
+	return k.name + "(" + k.decode + ")"
+}
+
+func writeUnmarshal(buf *bytes.Buffer, name string, fields []field) {
+	keysVar := "jsonKeys" + name
+	fmt.Fprintf(buf, "\nvar %s = [...]string{\n", keysVar)
+	for _, f := range fields {
+		fmt.Fprintf(buf, "%q,\n", f.key)
+	}
+	fmt.Fprintf(buf, `}
+
+// UnmarshalJSON implements json.Unmarshaler. Like encoding/json it matches
+// keys case-insensitively, ignores unknown keys and leaves fields that are
+// absent or null unchanged, but it stops at the first type error.
+func (v *%s) UnmarshalJSON(data []byte) error {
+	var l jsonwire.Lexer
+	l.Reset(data)
+	if l.Null() {
+		return l.Finish()
+	}
+	l.BeginObject()
+	for l.More('}') {
+		key := l.Key()
+		field := -1
+		switch string(key) {
+`, name)
+	for i, f := range fields {
+		fmt.Fprintf(buf, "case %q:\nfield = %d\n", f.key, i)
+	}
+	fmt.Fprintf(buf, "default:\nfield = jsonwire.FoldIndex(key, %s[:])\n}\nswitch field {\n", keysVar)
+	for i, f := range fields {
+		value := "v." + f.goName
+		fmt.Fprintf(buf, "case %d:\n", i)
+		if f.slice {
+			fmt.Fprintf(buf, `if l.Null() {
+	%[1]s = nil
+	break
+}
+if %[1]s == nil {
+	%[1]s = []%[2]s{}
+}
+%[1]s = %[1]s[:0]
+// As in encoding/json, a null element leaves a reused element unchanged.
+l.BeginArray()
+for i := 0; l.More(']'); i++ {
+	if i < cap(%[1]s) {
+		%[1]s = %[1]s[:i+1]
+	} else {
+		%[1]s = append(%[1]s, %[4]s)
+	}
+	if !l.Null() {
+		%[1]s[i] = %[3]s
+	}
+}
+`, value, f.kind.name, decode(f.kind), f.kind.zero)
+		} else {
+			fmt.Fprintf(buf, "if !l.Null() {\n%s = %s\n}\n", value, decode(f.kind))
+		}
+	}
+	fmt.Fprintf(buf, "default:\nl.Skip()\n}\n}\nreturn l.Finish()\n}\n")
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/evaluate_serde_protocol/internal/gostruct"
+)
+
+// TestGeneratedUpToDate checks that the checked-in code for AgentDataJSON
+// matches what jsongen generates now.
+func TestGeneratedUpToDate(t *testing.T) {
+	got, err := generate(filepath.Join("..", "..", "agentjson_test.go"), []string{"AgentDataJSON"}, defaultRuntime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := ioutil.ReadFile(filepath.Join("..", "..", "agentdatajson_json_test.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Error("agentdatajson_json_test.go is stale; run go generate")
+	}
+}
+
+// writeSource writes src to a Go file in a new temporary directory, and
+// returns its name and a function that removes the directory.
+func writeSource(t *testing.T, src string) (string, func()) {
+	dir, err := ioutil.TempDir("", "jsongen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "t.go")
+	if err := ioutil.WriteFile(file, []byte(src), 0644); err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	return file, func() { os.RemoveAll(dir) }
+}
+
+// TestKinds checks that every type gostruct accepts has a kind.
+func TestKinds(t *testing.T) {
+	for name := range gostruct.Types {
+		if k, ok := kinds[name]; !ok || k.name != name {
+			t.Errorf("no kind for %s", name)
+		}
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	for _, tc := range []struct {
+		src, err string
+	}{
+		{"type T struct{ F float64 }", "unsupported type"},
+		{"type T struct{ F []byte }", "unsupported type []byte"},
+		{"type T struct{ F map[string]string }", "unsupported type"},
+		{"type T struct{ U }; type U struct{}", "embedded"},
+		{"type T struct{ F int `json:\",string\"`}", "not supported"},
+		{"type T struct{ A int `json:\"a\"`; B int `json:\"a\"` }", "duplicate"},
+		{"type U struct{}", "no struct type T"},
+	} {
+		file, remove := writeSource(t, "package p\n"+tc.src+"\n")
+		_, err := generate(file, []string{"T"}, defaultRuntime)
+		remove()
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%s: error %v, want %q", tc.src, err, tc.err)
+		}
+	}
+}
+
+func TestGenerateTags(t *testing.T) {
+	src := "package p\ntype T struct {\n" +
+		"\tA string `json:\",omitempty\"`\n" +
+		"\tB bool `json:\"b,omitempty\"`\n" +
+		"\tC int8 `json:\"-\"`\n" +
+		"\tD []uint16 `json:\"-,\"`\n" +
+		"\te int\n" +
+		"}\n"
+	file, remove := writeSource(t, src)
+	defer remove()
+	out, err := generate(file, []string{"T"}, defaultRuntime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"if v.A != \"\" {\n\t\tb = append(b, `\"A\":`...)",
+		"if v.B {\n\t\tb = jsonwire.AppendComma(b)\n\t\tb = append(b, `\"b\":`...)",
+		"b = jsonwire.AppendComma(b)\n\tb = append(b, `\"-\":`...)",
+		"v.D[i] = uint16(l.Uint(16))",
+	} {
+		if !bytes.Contains(out, []byte(want)) {
+			t.Errorf("generated code lacks %q:\n%s", want, out)
+		}
+	}
+	for _, unwanted := range []string{"v.C", "v.e"} {
+		if bytes.Contains(out, []byte(unwanted)) {
+			t.Errorf("generated code encodes %s", unwanted)
+		}
+	}
+}
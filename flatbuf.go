@@ -0,0 +1,247 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// A flat buffer is a FlatBuffers-style table that is read in place, without
+// decoding it first:
+//
+//	root    uint32 offset of the table from the start of the buffer
+//	vtable  uint16 vtable size, uint16 table size, one uint16 per slot
+//	        holding the field's offset in the table, or 0 if absent
+//	table   int32 distance back to the vtable, then the inline fields
+//	data    strings and vectors referenced from the table
+//
+// Scalars are stored inline. Strings and vectors are stored after the table
+// and referenced by a uint32 offset relative to the referencing field.
+// Strings are a uint32 length followed by the bytes and a zero; vectors of
+// strings are a uint32 count followed by one offset per string. Unlike
+// FlatBuffers the buffer is built front to back and fields are not padded
+// for alignment, since they are read with encoding/binary.
+
+var errFlatCorrupt = errors.New("flatbuf: corrupt buffer")
+
+type flatKind uint8
+
+const (
+	flatInt64 flatKind = iota
+	flatString
+	flatStringVector
+)
+
+type flatField struct {
+	slot int
+	kind flatKind
+	i    int64
+	s    string
+	ss   []string
+	pos  int
+}
+
+// flatBuilder builds a flat buffer holding one table. Fields are added
+// between startTable and finish; the builder can be reused afterwards.
+type flatBuilder struct {
+	slots  int
+	fields []flatField
+}
+
+func (b *flatBuilder) startTable(slots int) {
+	b.slots = slots
+	b.fields = b.fields[:0]
+}
+
+func (b *flatBuilder) addInt64(slot int, v int64) {
+	b.fields = append(b.fields, flatField{slot: slot, kind: flatInt64, i: v})
+}
+
+func (b *flatBuilder) addString(slot int, s string) {
+	b.fields = append(b.fields, flatField{slot: slot, kind: flatString, s: s})
+}
+
+func (b *flatBuilder) addStringVector(slot int, ss []string) {
+	b.fields = append(b.fields, flatField{slot: slot, kind: flatStringVector, ss: ss})
+}
+
+// finish appends the table to buf and returns the extended slice.
+func (b *flatBuilder) finish(buf []byte) []byte {
+	start := len(buf)
+	buf = append(buf, 0, 0, 0, 0)
+
+	vtable := len(buf)
+	tableSize := 4
+	for i := range b.fields {
+		if b.fields[i].kind == flatInt64 {
+			tableSize += 8
+		} else {
+			tableSize += 4
+		}
+	}
+	buf = appendUint16LE(buf, uint16(4+2*b.slots))
+	buf = appendUint16LE(buf, uint16(tableSize))
+	for slot := 0; slot < b.slots; slot++ {
+		off, at := 0, 4
+		for i := range b.fields {
+			if b.fields[i].slot == slot {
+				off = at
+			}
+			if b.fields[i].kind == flatInt64 {
+				at += 8
+			} else {
+				at += 4
+			}
+		}
+		buf = appendUint16LE(buf, uint16(off))
+	}
+
+	table := len(buf)
+	binary.LittleEndian.PutUint32(buf[start:], uint32(table-start))
+	buf = appendUint32LE(buf, uint32(table-vtable))
+	for i := range b.fields {
+		f := &b.fields[i]
+		if f.kind == flatInt64 {
+			buf = appendUint64LE(buf, uint64(f.i))
+			continue
+		}
+		f.pos = len(buf)
+		buf = append(buf, 0, 0, 0, 0)
+	}
+
+	for i := range b.fields {
+		f := &b.fields[i]
+		switch f.kind {
+		case flatString:
+			buf = appendFlatString(putFlatOffset(buf, f.pos), f.s)
+		case flatStringVector:
+			buf = putFlatOffset(buf, f.pos)
+			buf = appendUint32LE(buf, uint32(len(f.ss)))
+			elems := len(buf)
+			for range f.ss {
+				buf = append(buf, 0, 0, 0, 0)
+			}
+			for j, s := range f.ss {
+				buf = appendFlatString(putFlatOffset(buf, elems+4*j), s)
+			}
+		}
+	}
+	return buf
+}
+
+// putFlatOffset points the offset at pos to the end of buf.
+func putFlatOffset(buf []byte, pos int) []byte {
+	binary.LittleEndian.PutUint32(buf[pos:], uint32(len(buf)-pos))
+	return buf
+}
+
+func appendFlatString(b []byte, s string) []byte {
+	b = appendUint32LE(b, uint32(len(s)))
+	b = append(b, s...)
+	return append(b, 0)
+}
+
+func appendUint16LE(b []byte, v uint16) []byte {
+	return append(b, byte(v), byte(v>>8))
+}
+
+// flatTable reads the fields of a table in place. flatRoot checks the root
+// offset and the vtable; offsets to strings and vectors are only checked
+// when they are read, and out of range ones read as absent fields.
+type flatTable struct {
+	buf    []byte
+	pos    int
+	vtable int
+	vsize  int
+}
+
+func flatRoot(buf []byte) (flatTable, error) {
+	t := flatTable{buf: buf}
+	if len(buf) < 4 {
+		return t, errFlatCorrupt
+	}
+	t.pos = int(binary.LittleEndian.Uint32(buf))
+	if t.pos < 4 || t.pos > len(buf)-4 {
+		return t, errFlatCorrupt
+	}
+	t.vtable = t.pos - int(int32(binary.LittleEndian.Uint32(buf[t.pos:])))
+	if t.vtable < 0 || t.vtable > len(buf)-4 {
+		return t, errFlatCorrupt
+	}
+	t.vsize = int(binary.LittleEndian.Uint16(buf[t.vtable:]))
+	tableSize := int(binary.LittleEndian.Uint16(buf[t.vtable+2:]))
+	if t.vsize < 4 || t.vtable+t.vsize > len(buf) || t.pos+tableSize > len(buf) {
+		return t, errFlatCorrupt
+	}
+	return t, nil
+}
+
+// field returns the position of the field in slot, or 0 if it is absent.
+func (t flatTable) field(slot, size int) int {
+	o := 4 + 2*slot
+	if o+2 > t.vsize {
+		return 0
+	}
+	off := int(binary.LittleEndian.Uint16(t.buf[t.vtable+o:]))
+	if off == 0 || t.pos+off+size > len(t.buf) {
+		return 0
+	}
+	return t.pos + off
+}
+
+// deref follows the offset at pos, returning 0 if it is out of range.
+func (t flatTable) deref(pos int) int {
+	if pos == 0 || pos > len(t.buf)-4 {
+		return 0
+	}
+	target := pos + int(binary.LittleEndian.Uint32(t.buf[pos:]))
+	if target <= pos || target > len(t.buf)-4 {
+		return 0
+	}
+	return target
+}
+
+func (t flatTable) stringAt(pos int) []byte {
+	if pos == 0 {
+		return nil
+	}
+	n := int(binary.LittleEndian.Uint32(t.buf[pos:]))
+	if n > len(t.buf)-pos-4 {
+		return nil
+	}
+	return t.buf[pos+4 : pos+4+n]
+}
+
+func (t flatTable) int64(slot int) int64 {
+	pos := t.field(slot, 8)
+	if pos == 0 {
+		return 0
+	}
+	return int64(binary.LittleEndian.Uint64(t.buf[pos:]))
+}
+
+// bytes returns the string in slot without copying it.
+func (t flatTable) bytes(slot int) []byte {
+	return t.stringAt(t.deref(t.field(slot, 4)))
+}
+
+func (t flatTable) vectorLen(slot int) int {
+	pos := t.deref(t.field(slot, 4))
+	if pos == 0 {
+		return 0
+	}
+	n := int(binary.LittleEndian.Uint32(t.buf[pos:]))
+	if n > (len(t.buf)-pos-4)/4 {
+		return 0
+	}
+	return n
+}
+
+// vectorBytes returns string i of the vector in slot without copying it.
+// i must be less than vectorLen(slot).
+func (t flatTable) vectorBytes(slot, i int) []byte {
+	pos := t.deref(t.field(slot, 4))
+	if pos == 0 {
+		return nil
+	}
+	return t.stringAt(t.deref(pos + 4 + 4*i))
+}
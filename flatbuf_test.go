@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// Slots of the AgentData table, in the field order of AgentProto.
+const (
+	flatHostname = iota
+	flatStatus
+	flatTimestamp
+	flatLsns
+	flatAgentSlots
+)
+
+// appendFlat appends a as a flat buffer table.
+func (a *AgentData) appendFlat(b *flatBuilder, buf []byte) []byte {
+	b.startTable(flatAgentSlots)
+	b.addString(flatHostname, a.Hostname)
+	b.addString(flatStatus, a.Status)
+	b.addInt64(flatTimestamp, int64(a.Timestamp))
+	b.addStringVector(flatLsns, a.Lsns)
+	return b.finish(buf)
+}
+
+// unmarshalFlat copies every field of the table in data into a.
+func (a *AgentData) unmarshalFlat(data []byte) error {
+	t, err := flatRoot(data)
+	if err != nil {
+		return err
+	}
+	a.Hostname = string(t.bytes(flatHostname))
+	a.Status = string(t.bytes(flatStatus))
+	a.Timestamp = int(t.int64(flatTimestamp))
+	a.Lsns = a.Lsns[:0]
+	for i, n := 0, t.vectorLen(flatLsns); i < n; i++ {
+		a.Lsns = append(a.Lsns, string(t.vectorBytes(flatLsns, i)))
+	}
+	return nil
+}
+
+func TestFlatRoundTrip(t *testing.T) {
+	want := generateObject()
+	out := want.appendFlat(&flatBuilder{}, nil)
+	got := &AgentData{}
+	if err := got.unmarshalFlat(out); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	// A table built with fewer slots reads the missing ones as absent.
+	b := &flatBuilder{}
+	b.startTable(1)
+	b.addString(flatHostname, "db1")
+	table, err := flatRoot(b.finish(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(table.bytes(flatHostname)) != "db1" || table.int64(flatTimestamp) != 0 || table.vectorLen(flatLsns) != 0 {
+		t.Errorf("table with one slot read as %q, %d, %d",
+			table.bytes(flatHostname), table.int64(flatTimestamp), table.vectorLen(flatLsns))
+	}
+}
+
+func TestFlatEncoding(t *testing.T) {
+	out := (&AgentData{Hostname: "h", Timestamp: -1, Lsns: []string{"x"}}).appendFlat(&flatBuilder{}, nil)
+	want := []byte{
+		16, 0, 0, 0, // root table
+		12, 0, 24, 0, 4, 0, 8, 0, 12, 0, 20, 0, // vtable
+		12, 0, 0, 0, // table, 12 bytes back to the vtable
+		20, 0, 0, 0, // hostname
+		22, 0, 0, 0, // status
+		0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, // timestamp
+		15, 0, 0, 0, // lsns
+		1, 0, 0, 0, 'h', 0,
+		0, 0, 0, 0, 0,
+		1, 0, 0, 0, 4, 0, 0, 0, 1, 0, 0, 0, 'x', 0,
+	}
+	if !bytes.Equal(out, want) {
+		t.Errorf("got % x, want % x", out, want)
+	}
+
+	// Every truncation must be rejected or read without panicking.
+	got := &AgentData{}
+	for n := 0; n < len(out); n++ {
+		got.unmarshalFlat(out[:n])
+	}
+}
+
+// BenchmarkFlatBuffers compares building and reading a flat buffer table in
+// place against marshalling and fully unmarshalling AgentProto.
+func BenchmarkFlatBuffers(b *testing.B) {
+	obj := generateObject()
+	flat := obj.appendFlat(&flatBuilder{}, nil)
+	pb, err := proto.Marshal(generateProtoBufObject())
+	if err != nil {
+		panic(err)
+	}
+
+	b.Run("build/flat", func(b *testing.B) {
+		builder := &flatBuilder{}
+		var out []byte
+		for n := 0; n < b.N; n++ {
+			out = obj.appendFlat(builder, out[:0])
+		}
+		b.ReportMetric(float64(len(out)), "bytes")
+	})
+	b.Run("build/proto", func(b *testing.B) {
+		obj := generateProtoBufObject()
+		var out []byte
+		for n := 0; n < b.N; n++ {
+			out, err = proto.MarshalOptions{}.MarshalAppend(out[:0], obj)
+			if err != nil {
+				panic(err)
+			}
+		}
+		b.ReportMetric(float64(len(out)), "bytes")
+	})
+
+	var sink int
+	b.Run("hostname/flat", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			t, err := flatRoot(flat)
+			if err != nil {
+				panic(err)
+			}
+			sink += len(t.bytes(flatHostname))
+		}
+	})
+	b.Run("hostname/proto", func(b *testing.B) {
+		obj := &AgentProto{}
+		for n := 0; n < b.N; n++ {
+			if err := proto.Unmarshal(pb, obj); err != nil {
+				panic(err)
+			}
+			sink += len(obj.Hostname)
+		}
+	})
+
+	// Reading all fields touches every value in place; only unmarshalFlat
+	// copies them into an AgentData.
+	b.Run("all/flat", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			t, err := flatRoot(flat)
+			if err != nil {
+				panic(err)
+			}
+			sink += len(t.bytes(flatHostname)) + len(t.bytes(flatStatus)) + int(t.int64(flatTimestamp))
+			for i, l := 0, t.vectorLen(flatLsns); i < l; i++ {
+				sink += len(t.vectorBytes(flatLsns, i))
+			}
+		}
+	})
+	b.Run("all/flatcopy", func(b *testing.B) {
+		obj := &AgentData{}
+		for n := 0; n < b.N; n++ {
+			if err := obj.unmarshalFlat(flat); err != nil {
+				panic(err)
+			}
+		}
+	})
+	b.Run("all/proto", func(b *testing.B) {
+		obj := &AgentProto{}
+		for n := 0; n < b.N; n++ {
+			if err := proto.Unmarshal(pb, obj); err != nil {
+				panic(err)
+			}
+			sink += len(obj.Hostname) + len(obj.Status) + int(obj.Timestamp)
+			for _, lsn := range obj.Lsns {
+				sink += len(lsn)
+			}
+		}
+	})
+}
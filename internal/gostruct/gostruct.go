@@ -0,0 +1,137 @@
+// Package gostruct reads the struct types that cmd/jsongen and cmd/bingen
+// generate code for, so that both accept the same fields.
+//
+// A field may be a string, a bool, an integer, or a slice of those, []byte
+// included. Embedded fields and any other type are rejected. Unexported
+// fields are skipped, as are fields whose tag for the generator's key is
+// "-".
+package gostruct
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Types is the set of type names a field, or the elements of a slice
+// field, may have.
+var Types = map[string]bool{
+	"string": true,
+	"bool":   true,
+	"byte":   true,
+	"rune":   true,
+}
+
+func init() {
+	for _, bits := range []string{"", "8", "16", "32", "64"} {
+		Types["int"+bits] = true
+		Types["uint"+bits] = true
+	}
+}
+
+// Field is a field to generate code for.
+type Field struct {
+	Name  string
+	Type  string // element type name if Slice, as written, e.g. "byte"
+	Slice bool
+	Tag   string // value of the tag for the generator's key
+	Pos   token.Position
+}
+
+// Bytes reports whether f is a []byte, which encodings often treat apart
+// from other slices.
+func (f Field) Bytes() bool {
+	return f.Slice && (f.Type == "byte" || f.Type == "uint8")
+}
+
+// File is a parsed Go source file.
+type File struct {
+	// Package is the name of the file's package.
+	Package string
+
+	name    string
+	fset    *token.FileSet
+	structs map[string]*ast.StructType
+}
+
+// ParseFile parses filename.
+func ParseFile(filename string) (*File, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	f := &File{Package: file.Name.Name, name: filename, fset: fset, structs: make(map[string]*ast.StructType)}
+	ast.Inspect(file, func(n ast.Node) bool {
+		if spec, ok := n.(*ast.TypeSpec); ok {
+			if st, ok := spec.Type.(*ast.StructType); ok {
+				f.structs[spec.Name.Name] = st
+			}
+		}
+		return true
+	})
+	return f, nil
+}
+
+// Fields returns the fields of the struct type name in declaration order,
+// with their tags for key.
+func (f *File) Fields(name, key string) ([]Field, error) {
+	st, ok := f.structs[name]
+	if !ok {
+		return nil, fmt.Errorf("%s: no struct type %s", f.name, name)
+	}
+	var fields []Field
+	for _, sf := range st.Fields.List {
+		if len(sf.Names) == 0 {
+			return nil, fmt.Errorf("%s: %s: embedded fields are not supported", f.fset.Position(sf.Pos()), name)
+		}
+		var tag string
+		if sf.Tag != nil {
+			unquoted, err := strconv.Unquote(sf.Tag.Value)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s: %v", f.fset.Position(sf.Tag.Pos()), name, err)
+			}
+			tag = reflect.StructTag(unquoted).Get(key)
+		}
+		if tag == "-" {
+			continue
+		}
+		typ, slice, ok := fieldType(sf.Type)
+		for _, ident := range sf.Names {
+			if !ident.IsExported() {
+				continue
+			}
+			pos := f.fset.Position(ident.Pos())
+			if !ok {
+				return nil, fmt.Errorf("%s: field %s.%s has an unsupported type", pos, name, ident.Name)
+			}
+			fields = append(fields, Field{Name: ident.Name, Type: typ, Slice: slice, Tag: tag, Pos: pos})
+		}
+	}
+	return fields, nil
+}
+
+func fieldType(expr ast.Expr) (typ string, slice, ok bool) {
+	if at, isArray := expr.(*ast.ArrayType); isArray && at.Len == nil {
+		slice = true
+		expr = at.Elt
+	}
+	ident, isIdent := expr.(*ast.Ident)
+	if !isIdent || !Types[ident.Name] {
+		return "", false, false
+	}
+	return ident.Name, slice, true
+}
+
+// SplitTag splits a tag into the name before its first comma and the
+// options after it.
+func SplitTag(tag string) (name string, options []string) {
+	if i := strings.Index(tag, ","); i >= 0 {
+		return tag[:i], strings.Split(tag[i+1:], ",")
+	}
+	return tag, nil
+}
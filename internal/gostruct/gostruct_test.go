@@ -0,0 +1,104 @@
+package gostruct
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// parseSource writes src to a Go file in a new temporary directory and
+// parses it.
+func parseSource(t *testing.T, src string) *File {
+	dir, err := ioutil.TempDir("", "gostruct")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "t.go")
+	if err := ioutil.WriteFile(filename, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	file, err := ParseFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestFields(t *testing.T) {
+	file := parseSource(t, "package p\ntype T struct {\n"+
+		"\tA, B string `k:\"a,opt\" other:\"-\"`\n"+
+		"\tC []byte\n"+
+		"\tD []rune `k:\"-\"`\n"+
+		"\tE int64 `k:\"-,\"`\n"+
+		"\tf float64\n"+
+		"}\n")
+	if file.Package != "p" {
+		t.Errorf("package %q, want p", file.Package)
+	}
+	fields, err := file.Fields("T", "k")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		Field
+		line int
+	}{
+		{Field{Name: "A", Type: "string", Tag: "a,opt"}, 3},
+		{Field{Name: "B", Type: "string", Tag: "a,opt"}, 3},
+		{Field{Name: "C", Type: "byte", Slice: true}, 4},
+		{Field{Name: "E", Type: "int64", Tag: "-,"}, 6},
+	}
+	if len(fields) != len(want) {
+		t.Fatalf("got %+v, want %+v", fields, want)
+	}
+	for i, f := range fields {
+		line := f.Pos.Line
+		f.Pos = want[i].Pos
+		if f != want[i].Field || line != want[i].line {
+			t.Errorf("field %d: got %+v on line %d, want %+v on line %d", i, f, line, want[i].Field, want[i].line)
+		}
+	}
+	if !fields[2].Bytes() || fields[0].Bytes() {
+		t.Error("Bytes reports the wrong fields")
+	}
+}
+
+func TestFieldsErrors(t *testing.T) {
+	for _, tc := range []struct {
+		src, err string
+	}{
+		{"type T struct{ F float64 }", "field T.F has an unsupported type"},
+		{"type T struct{ F map[string]string }", "unsupported type"},
+		{"type T struct{ F [4]byte }", "unsupported type"},
+		{"type T struct{ F *int }", "unsupported type"},
+		{"type T struct{ U }; type U struct{}", "embedded"},
+		{"type U struct{}", "no struct type T"},
+	} {
+		_, err := parseSource(t, "package p\n"+tc.src+"\n").Fields("T", "k")
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%s: error %v, want %q", tc.src, err, tc.err)
+		}
+	}
+}
+
+func TestSplitTag(t *testing.T) {
+	for _, tc := range []struct {
+		tag, name string
+		options   []string
+	}{
+		{"", "", nil},
+		{"a", "a", nil},
+		{",omitempty", "", []string{"omitempty"}},
+		{"a,b,c", "a", []string{"b", "c"}},
+		{"-,", "-", []string{""}},
+	} {
+		name, options := SplitTag(tc.tag)
+		if name != tc.name || !reflect.DeepEqual(options, tc.options) {
+			t.Errorf("SplitTag(%q) = %q, %q, want %q, %q", tc.tag, name, options, tc.name, tc.options)
+		}
+	}
+}
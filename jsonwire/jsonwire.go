@@ -0,0 +1,615 @@
+// Package jsonwire is the runtime for code generated by cmd/jsongen: append
+// functions that encode JSON exactly as encoding/json does, and a Lexer
+// that decodes it without reflection.
+package jsonwire
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+const hex = "0123456789abcdef"
+
+// AppendString appends s as a JSON string, escaped as json.Marshal escapes
+// it: HTML characters and U+2028, U+2029 become \u escapes and invalid
+// UTF-8 becomes U+FFFD.
+func AppendString(b []byte, s string) []byte {
+	b = append(b, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		if c := s[i]; c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' && c != '<' && c != '>' && c != '&' {
+				i++
+				continue
+			}
+			b = append(b, s[start:i]...)
+			switch c {
+			case '"', '\\':
+				b = append(b, '\\', c)
+			case '\b':
+				b = append(b, '\\', 'b')
+			case '\f':
+				b = append(b, '\\', 'f')
+			case '\n':
+				b = append(b, '\\', 'n')
+			case '\r':
+				b = append(b, '\\', 'r')
+			case '\t':
+				b = append(b, '\\', 't')
+			default:
+				b = append(b, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xf])
+			}
+			i++
+			start = i
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			b = append(b, s[start:i]...)
+			b = append(b, "\ufffd"...)
+			i += size
+			start = i
+			continue
+		}
+		if r == '\u2028' || r == '\u2029' {
+			b = append(b, s[start:i]...)
+			b = append(b, '\\', 'u', '2', '0', '2', hex[r&0xf])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	b = append(b, s[start:]...)
+	return append(b, '"')
+}
+
+// AppendInt appends v as a JSON number.
+func AppendInt(b []byte, v int64) []byte {
+	return strconv.AppendInt(b, v, 10)
+}
+
+// AppendUint appends v as a JSON number.
+func AppendUint(b []byte, v uint64) []byte {
+	return strconv.AppendUint(b, v, 10)
+}
+
+// AppendBool appends v as a JSON literal.
+func AppendBool(b []byte, v bool) []byte {
+	return strconv.AppendBool(b, v)
+}
+
+// AppendComma appends the comma separating object members unless b ends
+// with the opening brace.
+func AppendComma(b []byte) []byte {
+	if b[len(b)-1] == '{' {
+		return b
+	}
+	return append(b, ',')
+}
+
+// SyntaxError reports malformed JSON and the offset where it was detected.
+type SyntaxError struct {
+	Offset int
+	msg    string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("jsonwire: %s at offset %d", e.msg, e.Offset)
+}
+
+// maxDepth bounds the nesting Skip accepts, as encoding/json does.
+const maxDepth = 10000
+
+// Lexer reads one JSON value from a byte slice. Methods read the value they
+// are named after; a null where a scalar is expected reads as the zero
+// value. The first error is sticky: later reads return zero values and
+// Finish reports it.
+type Lexer struct {
+	data    []byte
+	pos     int
+	err     error
+	first   bool
+	scratch []byte
+}
+
+// Reset makes the lexer read data from the start.
+func (l *Lexer) Reset(data []byte) {
+	l.data = data
+	l.pos = 0
+	l.err = nil
+	l.first = false
+}
+
+// Finish checks that only whitespace follows the value and returns the
+// first error.
+func (l *Lexer) Finish() error {
+	if l.err == nil && l.skipSpace() {
+		l.fail("invalid character " + quoteChar(l.data[l.pos]) + " after top-level value")
+	}
+	return l.err
+}
+
+// Fail records err unless an error was already recorded.
+func (l *Lexer) Fail(err error) {
+	if l.err == nil {
+		l.err = err
+	}
+	l.pos = len(l.data)
+}
+
+func (l *Lexer) fail(msg string) {
+	if l.err == nil {
+		l.err = &SyntaxError{Offset: l.pos, msg: msg}
+	}
+	l.pos = len(l.data)
+}
+
+// skipSpace skips whitespace and reports whether any data remains.
+func (l *Lexer) skipSpace() bool {
+	for l.pos < len(l.data) {
+		switch l.data[l.pos] {
+		case ' ', '\t', '\n', '\r':
+			l.pos++
+		default:
+			return true
+		}
+	}
+	return false
+}
+
+// peek returns the next non-space byte, or 0 at the end of the data.
+func (l *Lexer) peek() byte {
+	if !l.skipSpace() {
+		return 0
+	}
+	return l.data[l.pos]
+}
+
+func (l *Lexer) expect(c byte, what string) bool {
+	if l.err != nil {
+		return false
+	}
+	switch got := l.peek(); got {
+	case c:
+		l.pos++
+		return true
+	case 0:
+		l.fail("unexpected end of JSON input")
+	default:
+		l.fail("invalid character " + quoteChar(got) + " " + what)
+	}
+	return false
+}
+
+func (l *Lexer) literal(lit string) bool {
+	if !bytes.HasPrefix(l.data[l.pos:], []byte(lit)) {
+		l.fail("invalid literal")
+		return false
+	}
+	l.pos += len(lit)
+	return true
+}
+
+// Null consumes a null literal if it is the next value.
+func (l *Lexer) Null() bool {
+	if l.err == nil && l.peek() == 'n' {
+		return l.literal("null")
+	}
+	return false
+}
+
+// BeginObject consumes the opening brace of an object.
+func (l *Lexer) BeginObject() {
+	if l.expect('{', "looking for beginning of object") {
+		l.first = true
+	}
+}
+
+// BeginArray consumes the opening bracket of an array.
+func (l *Lexer) BeginArray() {
+	if l.expect('[', "looking for beginning of array") {
+		l.first = true
+	}
+}
+
+// More reports whether another member or element follows in the object or
+// array closed by end, consuming the separating comma or the closing end.
+func (l *Lexer) More(end byte) bool {
+	if l.err != nil {
+		return false
+	}
+	first := l.first
+	l.first = false
+	c := l.peek()
+	if c == end {
+		l.pos++
+		return false
+	}
+	if first {
+		return true
+	}
+	return l.expect(',', "after object key:value pair")
+}
+
+// Key reads an object key and the colon after it. The key is only valid
+// until the next read.
+func (l *Lexer) Key() []byte {
+	if l.err != nil {
+		return nil
+	}
+	if l.peek() != '"' {
+		l.expect('"', "looking for beginning of object key string")
+		return nil
+	}
+	key := l.str()
+	l.expect(':', "after object key")
+	return key
+}
+
+// String reads a string.
+func (l *Lexer) String() string {
+	if l.Null() || l.err != nil {
+		return ""
+	}
+	if l.peek() != '"' {
+		l.typeError("string")
+		return ""
+	}
+	return string(l.str())
+}
+
+// str reads the string at l.pos, unescaping it into l.scratch if needed.
+func (l *Lexer) str() []byte {
+	start := l.pos + 1
+	ascii := true
+	for i := start; i < len(l.data); i++ {
+		switch c := l.data[i]; {
+		case c == '"':
+			l.pos = i + 1
+			if !ascii && !utf8.Valid(l.data[start:i]) {
+				return l.unescape(start)
+			}
+			return l.data[start:i]
+		case c >= utf8.RuneSelf:
+			ascii = false
+		case c == '\\':
+			return l.unescape(start)
+		case c < 0x20:
+			l.pos = i
+			l.fail("invalid character " + quoteChar(c) + " in string literal")
+			return nil
+		}
+	}
+	l.pos = len(l.data)
+	l.fail("unexpected end of JSON input")
+	return nil
+}
+
+func (l *Lexer) unescape(start int) []byte {
+	b := l.scratch[:0]
+	for i := start; i < len(l.data); {
+		c := l.data[i]
+		switch {
+		case c == '"':
+			l.pos = i + 1
+			l.scratch = b
+			return b
+		case c < 0x20:
+			l.pos = i
+			l.fail("invalid character " + quoteChar(c) + " in string literal")
+			return nil
+		case c == '\\':
+			if i+1 >= len(l.data) {
+				l.pos = len(l.data)
+				l.fail("unexpected end of JSON input")
+				return nil
+			}
+			i++
+			switch e := l.data[i]; e {
+			case '"', '\\', '/':
+				b = append(b, e)
+			case 'b':
+				b = append(b, '\b')
+			case 'f':
+				b = append(b, '\f')
+			case 'n':
+				b = append(b, '\n')
+			case 'r':
+				b = append(b, '\r')
+			case 't':
+				b = append(b, '\t')
+			case 'u':
+				r, ok := hex4(l.data[i+1:])
+				if !ok {
+					l.pos = i
+					l.fail("invalid character in \\u escape")
+					return nil
+				}
+				i += 4
+				if utf16.IsSurrogate(r) {
+					r2, ok := rune(-1), false
+					if i+6 < len(l.data) && l.data[i+1] == '\\' && l.data[i+2] == 'u' {
+						r2, ok = hex4(l.data[i+3:])
+					}
+					if r = utf16.DecodeRune(r, r2); ok && r != utf8.RuneError {
+						i += 6
+					} else {
+						r = utf8.RuneError
+					}
+				}
+				b = appendRune(b, r)
+			default:
+				l.pos = i
+				l.fail("invalid character " + quoteChar(e) + " in string escape code")
+				return nil
+			}
+			i++
+			continue
+		case c < utf8.RuneSelf:
+			b = append(b, c)
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRune(l.data[i:])
+		b = appendRune(b, r)
+		i += size
+	}
+	l.pos = len(l.data)
+	l.fail("unexpected end of JSON input")
+	return nil
+}
+
+func hex4(b []byte) (rune, bool) {
+	if len(b) < 4 {
+		return 0, false
+	}
+	var r rune
+	for _, c := range b[:4] {
+		switch {
+		case '0' <= c && c <= '9':
+			c -= '0'
+		case 'a' <= c && c <= 'f':
+			c -= 'a' - 10
+		case 'A' <= c && c <= 'F':
+			c -= 'A' - 10
+		default:
+			return 0, false
+		}
+		r = r<<4 | rune(c)
+	}
+	return r, true
+}
+
+func appendRune(b []byte, r rune) []byte {
+	var buf [utf8.UTFMax]byte
+	n := utf8.EncodeRune(buf[:], r)
+	return append(b, buf[:n]...)
+}
+
+// Bool reads true or false.
+func (l *Lexer) Bool() bool {
+	if l.Null() || l.err != nil {
+		return false
+	}
+	switch l.peek() {
+	case 't':
+		return l.literal("true")
+	case 'f':
+		l.literal("false")
+	default:
+		l.typeError("bool")
+	}
+	return false
+}
+
+// number returns the next number and whether it is an integer, checking
+// the JSON number grammar.
+func (l *Lexer) number() ([]byte, bool) {
+	start := l.pos
+	i := start
+	if i < len(l.data) && l.data[i] == '-' {
+		i++
+	}
+	digits := i
+	for i < len(l.data) && '0' <= l.data[i] && l.data[i] <= '9' {
+		i++
+	}
+	if i == digits || (l.data[digits] == '0' && i > digits+1) {
+		l.pos = i
+		l.fail("invalid number")
+		return nil, false
+	}
+	integer := true
+	if i < len(l.data) && l.data[i] == '.' {
+		integer = false
+		i++
+		frac := i
+		for i < len(l.data) && '0' <= l.data[i] && l.data[i] <= '9' {
+			i++
+		}
+		if i == frac {
+			l.pos = i
+			l.fail("invalid number")
+			return nil, false
+		}
+	}
+	if i < len(l.data) && (l.data[i] == 'e' || l.data[i] == 'E') {
+		integer = false
+		i++
+		if i < len(l.data) && (l.data[i] == '+' || l.data[i] == '-') {
+			i++
+		}
+		exp := i
+		for i < len(l.data) && '0' <= l.data[i] && l.data[i] <= '9' {
+			i++
+		}
+		if i == exp {
+			l.pos = i
+			l.fail("invalid number")
+			return nil, false
+		}
+	}
+	l.pos = i
+	return l.data[start:i], integer
+}
+
+// Int reads an integer that fits in bitSize bits, where 0 means int.
+func (l *Lexer) Int(bitSize int) int64 {
+	if l.Null() || l.err != nil {
+		return 0
+	}
+	if c := l.peek(); c != '-' && (c < '0' || c > '9') {
+		l.typeError("number")
+		return 0
+	}
+	start := l.pos
+	num, integer := l.number()
+	if l.err != nil {
+		return 0
+	}
+	if bitSize == 0 {
+		bitSize = strconv.IntSize
+	}
+	digits := num
+	neg := num[0] == '-'
+	if neg {
+		digits = num[1:]
+	}
+	var u uint64
+	ok := integer
+	for _, c := range digits {
+		if !ok || u > (1<<63)/10 {
+			ok = false
+			break
+		}
+		u = u*10 + uint64(c-'0')
+	}
+	limit := uint64(1) << uint(bitSize-1)
+	if !ok || (!neg && u >= limit) || (neg && u > limit) {
+		l.pos = start
+		l.fail("cannot unmarshal number " + string(num) + " into int" + strconv.Itoa(bitSize))
+		return 0
+	}
+	if neg {
+		return -int64(u)
+	}
+	return int64(u)
+}
+
+// Uint reads a non-negative integer that fits in bitSize bits, where 0
+// means uint.
+func (l *Lexer) Uint(bitSize int) uint64 {
+	if l.Null() || l.err != nil {
+		return 0
+	}
+	if c := l.peek(); c != '-' && (c < '0' || c > '9') {
+		l.typeError("number")
+		return 0
+	}
+	start := l.pos
+	num, integer := l.number()
+	if l.err != nil {
+		return 0
+	}
+	if bitSize == 0 {
+		bitSize = strconv.IntSize
+	}
+	v, err := strconv.ParseUint(string(num), 10, bitSize)
+	if !integer || err != nil {
+		l.pos = start
+		l.fail("cannot unmarshal number " + string(num) + " into uint" + strconv.Itoa(bitSize))
+		return 0
+	}
+	return v
+}
+
+// Skip reads and discards the next value.
+func (l *Lexer) Skip() {
+	l.skip(0)
+}
+
+func (l *Lexer) skip(depth int) {
+	if depth > maxDepth {
+		l.fail("exceeded max depth")
+		return
+	}
+	switch c := l.peek(); {
+	case c == '{':
+		l.BeginObject()
+		for l.More('}') {
+			l.Key()
+			l.skip(depth + 1)
+		}
+	case c == '[':
+		l.BeginArray()
+		for l.More(']') {
+			l.skip(depth + 1)
+		}
+	case c == '"':
+		l.str()
+	case c == 't':
+		l.literal("true")
+	case c == 'f':
+		l.literal("false")
+	case c == 'n':
+		l.literal("null")
+	case c == '-' || ('0' <= c && c <= '9'):
+		l.number()
+	case c == 0:
+		l.fail("unexpected end of JSON input")
+	default:
+		l.fail("invalid character " + quoteChar(c) + " looking for beginning of value")
+	}
+}
+
+// typeError fails on a value of the wrong type, after checking that it is
+// at least valid JSON.
+func (l *Lexer) typeError(want string) {
+	start := l.pos
+	l.Skip()
+	if l.err == nil {
+		l.pos = start
+		l.fail("cannot unmarshal " + describe(l.data[start]) + " into Go value of type " + want)
+	}
+}
+
+func describe(c byte) string {
+	switch c {
+	case '{':
+		return "object"
+	case '[':
+		return "array"
+	case '"':
+		return "string"
+	case 't', 'f':
+		return "bool"
+	}
+	return "number"
+}
+
+func quoteChar(c byte) string {
+	if c == '\'' {
+		return `'\''`
+	}
+	if c == '"' {
+		return `'"'`
+	}
+	s := strconv.Quote(string(c))
+	return "'" + s[1:len(s)-1] + "'"
+}
+
+// FoldIndex returns the index of the first name in names that equals key
+// under Unicode case-folding, or -1. Generated decoders use it for keys
+// that match no field exactly, as encoding/json does.
+func FoldIndex(key []byte, names []string) int {
+	for i, name := range names {
+		if bytes.EqualFold(key, []byte(name)) {
+			return i
+		}
+	}
+	return -1
+}
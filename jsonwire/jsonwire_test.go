@@ -0,0 +1,75 @@
+package jsonwire
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestAppendString(t *testing.T) {
+	var ascii []byte
+	for c := 0; c < 0x80; c++ {
+		ascii = append(ascii, byte(c))
+	}
+	for _, s := range []string{
+		"",
+		string(ascii),
+		"é\u2028\u2029\U0001F600",
+		"\xff\xc3(\xed\xa0\x80",
+	} {
+		want, err := json.Marshal(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := AppendString(nil, s); !bytes.Equal(got, want) {
+			t.Errorf("AppendString(%q) = %s, want %s", s, got, want)
+		}
+	}
+}
+
+func TestLexerString(t *testing.T) {
+	for _, s := range []string{
+		`"plain"`,
+		`"\"\\\/\b\f\n\r\té "`,
+		`"😀 \ud83d \ude00x \ud800A"`,
+		"\"\xff\xc3(\"",
+		`null`,
+	} {
+		var want string
+		if err := json.Unmarshal([]byte(s), &want); err != nil {
+			t.Fatal(err)
+		}
+		var l Lexer
+		l.Reset([]byte(s))
+		if got := l.String(); got != want || l.Finish() != nil {
+			t.Errorf("%s: got %q, %v, want %q", s, got, l.Finish(), want)
+		}
+	}
+}
+
+func TestLexerInt(t *testing.T) {
+	for _, tc := range []struct {
+		in      string
+		bitSize int
+		want    int64
+		ok      bool
+	}{
+		{"0", 8, 0, true},
+		{"-128", 8, -128, true},
+		{"127", 8, 127, true},
+		{"128", 8, 0, false},
+		{"-129", 8, 0, false},
+		{"-9223372036854775808", 64, -9223372036854775808, true},
+		{"9223372036854775808", 64, 0, false},
+		{"1e2", 64, 0, false},
+		{"-", 64, 0, false},
+		{`"1"`, 64, 0, false},
+	} {
+		var l Lexer
+		l.Reset([]byte(tc.in))
+		got := l.Int(tc.bitSize)
+		if err := l.Finish(); (err == nil) != tc.ok || got != tc.want {
+			t.Errorf("Int(%d) of %s = %d, %v", tc.bitSize, tc.in, got, err)
+		}
+	}
+}
@@ -147,6 +147,210 @@ func (x *AgentRequest) GetData() string {
 	return ""
 }
 
+type StatusAck struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	ReceivedLsns int64 `protobuf:"varint,1,opt,name=received_lsns,json=receivedLsns,proto3" json:"received_lsns,omitempty"`
+}
+
+func (x *StatusAck) Reset() {
+	*x = StatusAck{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_agent_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *StatusAck) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatusAck) ProtoMessage() {}
+
+func (x *StatusAck) ProtoReflect() protoreflect.Message {
+	mi := &file_agent_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatusAck.ProtoReflect.Descriptor instead.
+func (*StatusAck) Descriptor() ([]byte, []int) {
+	return file_agent_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *StatusAck) GetReceivedLsns() int64 {
+	if x != nil {
+		return x.ReceivedLsns
+	}
+	return 0
+}
+
+type ArithArgs struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	A int64 `protobuf:"varint,1,opt,name=a,proto3" json:"a,omitempty"`
+	B int64 `protobuf:"varint,2,opt,name=b,proto3" json:"b,omitempty"`
+}
+
+func (x *ArithArgs) Reset() {
+	*x = ArithArgs{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_agent_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ArithArgs) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ArithArgs) ProtoMessage() {}
+
+func (x *ArithArgs) ProtoReflect() protoreflect.Message {
+	mi := &file_agent_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ArithArgs.ProtoReflect.Descriptor instead.
+func (*ArithArgs) Descriptor() ([]byte, []int) {
+	return file_agent_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ArithArgs) GetA() int64 {
+	if x != nil {
+		return x.A
+	}
+	return 0
+}
+
+func (x *ArithArgs) GetB() int64 {
+	if x != nil {
+		return x.B
+	}
+	return 0
+}
+
+type ArithProduct struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Product int64 `protobuf:"varint,1,opt,name=product,proto3" json:"product,omitempty"`
+}
+
+func (x *ArithProduct) Reset() {
+	*x = ArithProduct{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_agent_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ArithProduct) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ArithProduct) ProtoMessage() {}
+
+func (x *ArithProduct) ProtoReflect() protoreflect.Message {
+	mi := &file_agent_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ArithProduct.ProtoReflect.Descriptor instead.
+func (*ArithProduct) Descriptor() ([]byte, []int) {
+	return file_agent_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ArithProduct) GetProduct() int64 {
+	if x != nil {
+		return x.Product
+	}
+	return 0
+}
+
+type ArithQuotient struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Quo int64 `protobuf:"varint,1,opt,name=quo,proto3" json:"quo,omitempty"`
+	Rem int64 `protobuf:"varint,2,opt,name=rem,proto3" json:"rem,omitempty"`
+}
+
+func (x *ArithQuotient) Reset() {
+	*x = ArithQuotient{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_agent_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ArithQuotient) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ArithQuotient) ProtoMessage() {}
+
+func (x *ArithQuotient) ProtoReflect() protoreflect.Message {
+	mi := &file_agent_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ArithQuotient.ProtoReflect.Descriptor instead.
+func (*ArithQuotient) Descriptor() ([]byte, []int) {
+	return file_agent_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ArithQuotient) GetQuo() int64 {
+	if x != nil {
+		return x.Quo
+	}
+	return 0
+}
+
+func (x *ArithQuotient) GetRem() int64 {
+	if x != nil {
+		return x.Rem
+	}
+	return 0
+}
+
 var File_agent_proto protoreflect.FileDescriptor
 
 var file_agent_proto_rawDesc = []byte{
@@ -160,12 +364,35 @@ var file_agent_proto_rawDesc = []byte{
 	0x74, 0x61, 0x6d, 0x70, 0x12, 0x12, 0x0a, 0x04, 0x6c, 0x73, 0x6e, 0x73, 0x18, 0x04, 0x20, 0x03,
 	0x28, 0x09, 0x52, 0x04, 0x6c, 0x73, 0x6e, 0x73, 0x22, 0x22, 0x0a, 0x0c, 0x41, 0x67, 0x65, 0x6e,
 	0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x64, 0x61, 0x74, 0x61,
-	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x64, 0x61, 0x74, 0x61, 0x32, 0x44, 0x0a, 0x05,
-	0x41, 0x67, 0x65, 0x6e, 0x74, 0x12, 0x3b, 0x0a, 0x0f, 0x53, 0x65, 0x72, 0x76, 0x65, 0x41, 0x67,
-	0x65, 0x6e, 0x74, 0x50, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x13, 0x2e, 0x61, 0x67, 0x65, 0x6e, 0x74,
-	0x2e, 0x41, 0x67, 0x65, 0x6e, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x11, 0x2e,
-	0x61, 0x67, 0x65, 0x6e, 0x74, 0x2e, 0x41, 0x67, 0x65, 0x6e, 0x74, 0x50, 0x72, 0x6f, 0x74, 0x6f,
-	0x22, 0x00, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x64, 0x61, 0x74, 0x61, 0x22, 0x30, 0x0a, 0x09,
+	0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x41, 0x63, 0x6b, 0x12, 0x23, 0x0a, 0x0d, 0x72, 0x65, 0x63,
+	0x65, 0x69, 0x76, 0x65, 0x64, 0x5f, 0x6c, 0x73, 0x6e, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03,
+	0x52, 0x0c, 0x72, 0x65, 0x63, 0x65, 0x69, 0x76, 0x65, 0x64, 0x4c, 0x73, 0x6e, 0x73, 0x22, 0x27,
+	0x0a, 0x09, 0x41, 0x72, 0x69, 0x74, 0x68, 0x41, 0x72, 0x67, 0x73, 0x12, 0x0c, 0x0a, 0x01, 0x61,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x01, 0x61, 0x12, 0x0c, 0x0a, 0x01, 0x62, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x03, 0x52, 0x01, 0x62, 0x22, 0x28, 0x0a, 0x0c, 0x41, 0x72, 0x69, 0x74, 0x68,
+	0x50, 0x72, 0x6f, 0x64, 0x75, 0x63, 0x74, 0x12, 0x18, 0x0a, 0x07, 0x70, 0x72, 0x6f, 0x64, 0x75,
+	0x63, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x07, 0x70, 0x72, 0x6f, 0x64, 0x75, 0x63,
+	0x74, 0x22, 0x33, 0x0a, 0x0d, 0x41, 0x72, 0x69, 0x74, 0x68, 0x51, 0x75, 0x6f, 0x74, 0x69, 0x65,
+	0x6e, 0x74, 0x12, 0x10, 0x0a, 0x03, 0x71, 0x75, 0x6f, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52,
+	0x03, 0x71, 0x75, 0x6f, 0x12, 0x10, 0x0a, 0x03, 0x72, 0x65, 0x6d, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x03, 0x52, 0x03, 0x72, 0x65, 0x6d, 0x32, 0x7f, 0x0a, 0x05, 0x41, 0x67, 0x65, 0x6e, 0x74, 0x12,
+	0x3b, 0x0a, 0x0f, 0x53, 0x65, 0x72, 0x76, 0x65, 0x41, 0x67, 0x65, 0x6e, 0x74, 0x50, 0x72, 0x6f,
+	0x74, 0x6f, 0x12, 0x13, 0x2e, 0x61, 0x67, 0x65, 0x6e, 0x74, 0x2e, 0x41, 0x67, 0x65, 0x6e, 0x74,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x11, 0x2e, 0x61, 0x67, 0x65, 0x6e, 0x74, 0x2e,
+	0x41, 0x67, 0x65, 0x6e, 0x74, 0x50, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0x00, 0x12, 0x39, 0x0a, 0x10,
+	0x52, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x41, 0x67, 0x65, 0x6e, 0x74, 0x50, 0x72, 0x6f, 0x74, 0x6f,
+	0x12, 0x11, 0x2e, 0x61, 0x67, 0x65, 0x6e, 0x74, 0x2e, 0x41, 0x67, 0x65, 0x6e, 0x74, 0x50, 0x72,
+	0x6f, 0x74, 0x6f, 0x1a, 0x10, 0x2e, 0x61, 0x67, 0x65, 0x6e, 0x74, 0x2e, 0x53, 0x74, 0x61, 0x74,
+	0x75, 0x73, 0x41, 0x63, 0x6b, 0x22, 0x00, 0x32, 0x70, 0x0a, 0x05, 0x41, 0x72, 0x69, 0x74, 0x68,
+	0x12, 0x33, 0x0a, 0x08, 0x4d, 0x75, 0x6c, 0x74, 0x69, 0x70, 0x6c, 0x79, 0x12, 0x10, 0x2e, 0x61,
+	0x67, 0x65, 0x6e, 0x74, 0x2e, 0x41, 0x72, 0x69, 0x74, 0x68, 0x41, 0x72, 0x67, 0x73, 0x1a, 0x13,
+	0x2e, 0x61, 0x67, 0x65, 0x6e, 0x74, 0x2e, 0x41, 0x72, 0x69, 0x74, 0x68, 0x50, 0x72, 0x6f, 0x64,
+	0x75, 0x63, 0x74, 0x22, 0x00, 0x12, 0x32, 0x0a, 0x06, 0x44, 0x69, 0x76, 0x69, 0x64, 0x65, 0x12,
+	0x10, 0x2e, 0x61, 0x67, 0x65, 0x6e, 0x74, 0x2e, 0x41, 0x72, 0x69, 0x74, 0x68, 0x41, 0x72, 0x67,
+	0x73, 0x1a, 0x14, 0x2e, 0x61, 0x67, 0x65, 0x6e, 0x74, 0x2e, 0x41, 0x72, 0x69, 0x74, 0x68, 0x51,
+	0x75, 0x6f, 0x74, 0x69, 0x65, 0x6e, 0x74, 0x22, 0x00, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x33,
 }
 
 var (
@@ -180,16 +407,26 @@ func file_agent_proto_rawDescGZIP() []byte {
 	return file_agent_proto_rawDescData
 }
 
-var file_agent_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_agent_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
 var file_agent_proto_goTypes = []interface{}{
-	(*AgentProto)(nil),   // 0: agent.AgentProto
-	(*AgentRequest)(nil), // 1: agent.AgentRequest
+	(*AgentProto)(nil),    // 0: agent.AgentProto
+	(*AgentRequest)(nil),  // 1: agent.AgentRequest
+	(*StatusAck)(nil),     // 2: agent.StatusAck
+	(*ArithArgs)(nil),     // 3: agent.ArithArgs
+	(*ArithProduct)(nil),  // 4: agent.ArithProduct
+	(*ArithQuotient)(nil), // 5: agent.ArithQuotient
 }
 var file_agent_proto_depIdxs = []int32{
 	1, // 0: agent.Agent.ServeAgentProto:input_type -> agent.AgentRequest
-	0, // 1: agent.Agent.ServeAgentProto:output_type -> agent.AgentProto
-	1, // [1:2] is the sub-list for method output_type
-	0, // [0:1] is the sub-list for method input_type
+	0, // 1: agent.Agent.ReportAgentProto:input_type -> agent.AgentProto
+	3, // 2: agent.Arith.Multiply:input_type -> agent.ArithArgs
+	3, // 3: agent.Arith.Divide:input_type -> agent.ArithArgs
+	0, // 4: agent.Agent.ServeAgentProto:output_type -> agent.AgentProto
+	2, // 5: agent.Agent.ReportAgentProto:output_type -> agent.StatusAck
+	4, // 6: agent.Arith.Multiply:output_type -> agent.ArithProduct
+	5, // 7: agent.Arith.Divide:output_type -> agent.ArithQuotient
+	4, // [4:8] is the sub-list for method output_type
+	0, // [0:4] is the sub-list for method input_type
 	0, // [0:0] is the sub-list for extension type_name
 	0, // [0:0] is the sub-list for extension extendee
 	0, // [0:0] is the sub-list for field type_name
@@ -225,6 +462,54 @@ func file_agent_proto_init() {
 				return nil
 			}
 		}
+		file_agent_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*StatusAck); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_agent_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ArithArgs); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_agent_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ArithProduct); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_agent_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ArithQuotient); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
@@ -232,9 +517,9 @@ func file_agent_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_agent_proto_rawDesc,
 			NumEnums:      0,
-			NumMessages:   2,
+			NumMessages:   6,
 			NumExtensions: 0,
-			NumServices:   1,
+			NumServices:   2,
 		},
 		GoTypes:           file_agent_proto_goTypes,
 		DependencyIndexes: file_agent_proto_depIdxs,
@@ -259,6 +544,7 @@ const _ = grpc.SupportPackageIsVersion6
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
 type AgentClient interface {
 	ServeAgentProto(ctx context.Context, in *AgentRequest, opts ...grpc.CallOption) (*AgentProto, error)
+	ReportAgentProto(ctx context.Context, in *AgentProto, opts ...grpc.CallOption) (*StatusAck, error)
 }
 
 type agentClient struct {
@@ -278,9 +564,19 @@ func (c *agentClient) ServeAgentProto(ctx context.Context, in *AgentRequest, opt
 	return out, nil
 }
 
+func (c *agentClient) ReportAgentProto(ctx context.Context, in *AgentProto, opts ...grpc.CallOption) (*StatusAck, error) {
+	out := new(StatusAck)
+	err := c.cc.Invoke(ctx, "/agent.Agent/ReportAgentProto", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // AgentServer is the server API for Agent service.
 type AgentServer interface {
 	ServeAgentProto(context.Context, *AgentRequest) (*AgentProto, error)
+	ReportAgentProto(context.Context, *AgentProto) (*StatusAck, error)
 }
 
 // UnimplementedAgentServer can be embedded to have forward compatible implementations.
@@ -290,6 +586,9 @@ type UnimplementedAgentServer struct {
 func (*UnimplementedAgentServer) ServeAgentProto(context.Context, *AgentRequest) (*AgentProto, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ServeAgentProto not implemented")
 }
+func (*UnimplementedAgentServer) ReportAgentProto(context.Context, *AgentProto) (*StatusAck, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReportAgentProto not implemented")
+}
 
 func RegisterAgentServer(s *grpc.Server, srv AgentServer) {
 	s.RegisterService(&_Agent_serviceDesc, srv)
@@ -313,6 +612,24 @@ func _Agent_ServeAgentProto_Handler(srv interface{}, ctx context.Context, dec fu
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Agent_ReportAgentProto_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AgentProto)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServer).ReportAgentProto(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/agent.Agent/ReportAgentProto",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServer).ReportAgentProto(ctx, req.(*AgentProto))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _Agent_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "agent.Agent",
 	HandlerType: (*AgentServer)(nil),
@@ -321,6 +638,118 @@ var _Agent_serviceDesc = grpc.ServiceDesc{
 			MethodName: "ServeAgentProto",
 			Handler:    _Agent_ServeAgentProto_Handler,
 		},
+		{
+			MethodName: "ReportAgentProto",
+			Handler:    _Agent_ReportAgentProto_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "agent.proto",
+}
+
+// ArithClient is the client API for Arith service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type ArithClient interface {
+	Multiply(ctx context.Context, in *ArithArgs, opts ...grpc.CallOption) (*ArithProduct, error)
+	Divide(ctx context.Context, in *ArithArgs, opts ...grpc.CallOption) (*ArithQuotient, error)
+}
+
+type arithClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewArithClient(cc grpc.ClientConnInterface) ArithClient {
+	return &arithClient{cc}
+}
+
+func (c *arithClient) Multiply(ctx context.Context, in *ArithArgs, opts ...grpc.CallOption) (*ArithProduct, error) {
+	out := new(ArithProduct)
+	err := c.cc.Invoke(ctx, "/agent.Arith/Multiply", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *arithClient) Divide(ctx context.Context, in *ArithArgs, opts ...grpc.CallOption) (*ArithQuotient, error) {
+	out := new(ArithQuotient)
+	err := c.cc.Invoke(ctx, "/agent.Arith/Divide", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ArithServer is the server API for Arith service.
+type ArithServer interface {
+	Multiply(context.Context, *ArithArgs) (*ArithProduct, error)
+	Divide(context.Context, *ArithArgs) (*ArithQuotient, error)
+}
+
+// UnimplementedArithServer can be embedded to have forward compatible implementations.
+type UnimplementedArithServer struct {
+}
+
+func (*UnimplementedArithServer) Multiply(context.Context, *ArithArgs) (*ArithProduct, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Multiply not implemented")
+}
+func (*UnimplementedArithServer) Divide(context.Context, *ArithArgs) (*ArithQuotient, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Divide not implemented")
+}
+
+func RegisterArithServer(s *grpc.Server, srv ArithServer) {
+	s.RegisterService(&_Arith_serviceDesc, srv)
+}
+
+func _Arith_Multiply_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ArithArgs)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ArithServer).Multiply(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/agent.Arith/Multiply",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ArithServer).Multiply(ctx, req.(*ArithArgs))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Arith_Divide_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ArithArgs)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ArithServer).Divide(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/agent.Arith/Divide",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ArithServer).Divide(ctx, req.(*ArithArgs))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _Arith_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "agent.Arith",
+	HandlerType: (*ArithServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Multiply",
+			Handler:    _Arith_Multiply_Handler,
+		},
+		{
+			MethodName: "Divide",
+			Handler:    _Arith_Divide_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "agent.proto",
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	pb "github.com/evaluate_serde_protocol/protocol/agent"
+	"google.golang.org/grpc/codes"
+)
+
+// ArithPath is the prefix under which ArithHTTPHandler serves
+// ArithPath+"multiply" and ArithPath+"divide".
+const ArithPath = "/arith/"
+
+// ArithGRPCServer serves an Arith as the agent.Arith gRPC service.
+type ArithGRPCServer struct {
+	arith *Arith
+}
+
+// NewArithGRPCServer returns a pb.ArithServer backed by arith.
+func NewArithGRPCServer(arith *Arith) *ArithGRPCServer {
+	return &ArithGRPCServer{arith: arith}
+}
+
+func (s *ArithGRPCServer) Multiply(ctx context.Context, in *pb.ArithArgs) (*pb.ArithProduct, error) {
+	var product int
+	if err := s.arith.Multiply(&Args{int(in.A), int(in.B)}, &product); err != nil {
+		return nil, err
+	}
+	return &pb.ArithProduct{Product: int64(product)}, nil
+}
+
+func (s *ArithGRPCServer) Divide(ctx context.Context, in *pb.ArithArgs) (*pb.ArithQuotient, error) {
+	var quo Quotient
+	if err := s.arith.Divide(&Args{int(in.A), int(in.B)}, &quo); err != nil {
+		return nil, err
+	}
+	return &pb.ArithQuotient{Quo: int64(quo.Quo), Rem: int64(quo.Rem)}, nil
+}
+
+// ArithHTTPHandler serves an Arith over plain HTTP. Operands are passed as
+// the a and b query parameters and results are returned as JSON; failures
+// are written with WriteError.
+type ArithHTTPHandler struct {
+	arith *Arith
+}
+
+// NewArithHTTPHandler returns a handler backed by arith, to be mounted at
+// ArithPath.
+func NewArithHTTPHandler(arith *Arith) *ArithHTTPHandler {
+	return &ArithHTTPHandler{arith: arith}
+}
+
+func (h *ArithHTTPHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a, errA := strconv.Atoi(r.URL.Query().Get("a"))
+	b, errB := strconv.Atoi(r.URL.Query().Get("b"))
+	if errA != nil || errB != nil {
+		WriteError(w, Errorf(codes.InvalidArgument, "a and b must be integers"))
+		return
+	}
+
+	var reply interface{}
+	var err error
+	switch r.URL.Path {
+	case ArithPath + "multiply":
+		var product int
+		err = h.arith.Multiply(&Args{a, b}, &product)
+		reply = product
+	case ArithPath + "divide":
+		var quo Quotient
+		err = h.arith.Divide(&Args{a, b}, &quo)
+		reply = quo
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	out, err := json.Marshal(reply)
+	if err != nil {
+		WriteError(w, Errorf(codes.Internal, "%v", err))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(out)
+}
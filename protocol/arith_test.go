@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"strconv"
+	"testing"
+
+	pb "github.com/evaluate_serde_protocol/protocol/agent"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type divideFunc func(a, b int) (Quotient, error)
+
+// arithTransport calls Arith.Divide over one transport.
+type arithTransport struct {
+	name string
+	dial func() (divide divideFunc, close func())
+	// native reports whether err has the type this transport's client
+	// returns for application errors.
+	native func(err error) bool
+	// code is the CallError code a divide by zero surfaces as.
+	code codes.Code
+}
+
+func isServerError(err error) bool {
+	_, ok := err.(rpc.ServerError)
+	return ok
+}
+
+func rpcDivide(client *rpc.Client, err error) (divideFunc, func()) {
+	if err != nil {
+		panic(err)
+	}
+	return func(a, b int) (Quotient, error) {
+		var quo Quotient
+		err := client.Call("Arithmetic.Divide", &Args{a, b}, &quo)
+		return quo, err
+	}, func() { client.Close() }
+}
+
+func jsonrpc2Divide(client *JSONRPCClient, err error) (divideFunc, func()) {
+	if err != nil {
+		panic(err)
+	}
+	return func(a, b int) (Quotient, error) {
+		var quo Quotient
+		err := client.Call("Arithmetic.Divide", &Args{a, b}, &quo)
+		return quo, err
+	}, func() { client.Close() }
+}
+
+var arithTransports = []arithTransport{
+	{"TCPRPC", func() (divideFunc, func()) {
+		startTCPRPCServer()
+		return rpcDivide(rpc.Dial("tcp", "127.0.0.1:8081"))
+	}, isServerError, codes.InvalidArgument},
+	{"JSONRPC", func() (divideFunc, func()) {
+		startJSONRPCServer()
+		return rpcDivide(jsonrpc.Dial("tcp", "127.0.0.1:8082"))
+	}, isServerError, codes.InvalidArgument},
+	{"HTTPRPC", func() (divideFunc, func()) {
+		startHTTPRPCServer()
+		return rpcDivide(rpc.DialHTTP("tcp", "127.0.0.1:8083"))
+	}, isServerError, codes.InvalidArgument},
+	{"JSONRPC2", func() (divideFunc, func()) {
+		startJSONRPC2Server()
+		return jsonrpc2Divide(DialJSONRPC("tcp", "127.0.0.1:8086"))
+	}, func(err error) bool {
+		e, ok := err.(*JSONRPCError)
+		return ok && e.Code == JSONRPCServerError
+	}, codes.InvalidArgument},
+	{"JSONRPC2HTTP", func() (divideFunc, func()) {
+		startJSONRPC2Server()
+		return jsonrpc2Divide(NewJSONRPCHTTPClient(&http.Client{}, jsonrpc2HTTPURL), nil)
+	}, func(err error) bool {
+		e, ok := err.(*JSONRPCError)
+		return ok && e.Code == JSONRPCServerError
+	}, codes.InvalidArgument},
+	{"GRPC", func() (divideFunc, func()) {
+		startGRPCServer()
+		conn, err := grpc.Dial("127.0.0.1:8084", grpc.WithInsecure())
+		if err != nil {
+			panic(err)
+		}
+		client := pb.NewArithClient(conn)
+		return func(a, b int) (Quotient, error) {
+			quo, err := client.Divide(context.Background(), &pb.ArithArgs{A: int64(a), B: int64(b)})
+			if err != nil {
+				return Quotient{}, err
+			}
+			return Quotient{int(quo.Quo), int(quo.Rem)}, nil
+		}, func() { conn.Close() }
+	}, func(err error) bool {
+		s, ok := status.FromError(err)
+		return ok && s.Code() == codes.InvalidArgument
+	}, codes.InvalidArgument},
+	{"HTTP", func() (divideFunc, func()) {
+		startHTTPServer()
+		client := &http.Client{}
+		return func(a, b int) (Quotient, error) {
+			var quo Quotient
+			res, err := client.Get("http://127.0.0.1:8080" + ArithPath + "divide?a=" + strconv.Itoa(a) + "&b=" + strconv.Itoa(b))
+			if err != nil {
+				return quo, err
+			}
+			if err := CheckHTTPResponse(res); err != nil {
+				return quo, err
+			}
+			defer res.Body.Close()
+			err = json.NewDecoder(res.Body).Decode(&quo)
+			return quo, err
+		}, client.CloseIdleConnections
+	}, func(err error) bool {
+		e, ok := err.(*HTTPError)
+		return ok && e.StatusCode == http.StatusBadRequest
+	}, codes.InvalidArgument},
+}
+
+func TestArithDivide(t *testing.T) {
+	for _, transport := range arithTransports {
+		divide, close := transport.dial()
+
+		quo, err := divide(7, 2)
+		if err != nil {
+			t.Fatalf("%s: 7/2: %v", transport.name, err)
+		}
+		if quo != (Quotient{3, 1}) {
+			t.Errorf("%s: 7/2 = %+v, want {Quo:3 Rem:1}", transport.name, quo)
+		}
+
+		_, err = divide(1, 0)
+		if err == nil {
+			t.Fatalf("%s: 1/0 succeeded", transport.name)
+		}
+		if !transport.native(err) {
+			t.Errorf("%s: 1/0 returned %T %v", transport.name, err, err)
+		}
+		callErr := ToCallError(err)
+		if callErr.Code != transport.code || callErr.Message != ErrDivideByZero.Message {
+			t.Errorf("%s: 1/0 = %v, want %s: %s", transport.name, callErr, transport.code, ErrDivideByZero.Message)
+		}
+		close()
+	}
+}
+
+func TestToCallError(t *testing.T) {
+	tests := []struct {
+		err  error
+		code codes.Code
+	}{
+		{rpc.ServerError("rpc: can't find method Arithmetic.Missing"), codes.Unimplemented},
+		{rpc.ErrShutdown, codes.Unavailable},
+		{&JSONRPCError{Code: JSONRPCMethodNotFound, Message: "method not found"}, codes.Unimplemented},
+		{&JSONRPCError{Code: JSONRPCInvalidParams, Message: "bad params"}, codes.InvalidArgument},
+		{&HTTPError{StatusCode: http.StatusServiceUnavailable}, codes.Unavailable},
+		{status.Error(codes.PermissionDenied, "denied"), codes.PermissionDenied},
+	}
+	for _, test := range tests {
+		if got := ToCallError(test.err); got.Code != test.code || got.Err != test.err {
+			t.Errorf("ToCallError(%v) = %v, want code %s", test.err, got, test.code)
+		}
+	}
+	if ToCallError(nil) != nil {
+		t.Error("ToCallError(nil) != nil")
+	}
+}
+
+// BenchmarkArithDivide compares a successful Arith.Divide with one failing
+// on a zero divisor, over every transport.
+func BenchmarkArithDivide(b *testing.B) {
+	for _, transport := range arithTransports {
+		b.Run(transport.name, func(b *testing.B) {
+			divide, close := transport.dial()
+			defer close()
+
+			b.Run("ok", func(b *testing.B) {
+				for n := 0; n < b.N; n++ {
+					if _, err := divide(n, 7); err != nil {
+						panic(err)
+					}
+				}
+			})
+			b.Run("error", func(b *testing.B) {
+				for n := 0; n < b.N; n++ {
+					if _, err := divide(n, 0); err == nil {
+						panic("divide by zero succeeded")
+					}
+				}
+			})
+		})
+	}
+}
@@ -1,7 +1,7 @@
 package main
 
 import (
-    //"crypto/tls"
+    "crypto/tls"
     "encoding/json"
     "fmt"
     "io/ioutil"
@@ -9,16 +9,22 @@ import (
     "net/http"
     "net/rpc"
     "net/rpc/jsonrpc"
+    "strconv"
+    "strings"
+    "sync"
     "testing"
 
     pb "github.com/evaluate_serde_protocol/protocol/agent"
     "golang.org/x/net/context"
     "google.golang.org/grpc"
+    "google.golang.org/grpc/codes"
+    "google.golang.org/grpc/health"
     "google.golang.org/protobuf/proto"
 )
 
 var tcpHandler, jsonHandler, httpHandler, grpcHandler *AgentHandler
 var httpServer, httpsServer *http.Server
+var grpcHealthServer *health.Server
 
 type AgentHandler struct {}
 
@@ -29,6 +35,10 @@ type AgentData struct {
     Lsns        []string `json:"lsns"`
 }
 
+type StatusAck struct {
+    ReceivedLsns int64 `json:"received_lsns"`
+}
+
 func generateObject() *AgentData {
     return &AgentData{
         Hostname:   "10.64.6.138",
@@ -58,18 +68,93 @@ func (th *AgentHandler) ServeAgentProto(ctx context.Context, in *pb.AgentRequest
     }, nil
 }
 
+func (th *AgentHandler) ReportStatus(arg *AgentData, reply *StatusAck) error {
+    reply.ReceivedLsns = int64(len(arg.Lsns))
+    return nil
+}
+
+func (th *AgentHandler) ReportAgentProto(ctx context.Context, in *pb.AgentProto) (*pb.StatusAck, error) {
+    return &pb.StatusAck{ReceivedLsns: int64(len(in.Lsns))}, nil
+}
+
 func (th *AgentHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-    w.Header().Set("Content-Type", "application/json")
-    out, _ := json.Marshal(generateObject())
+    if r.Method == http.MethodPost {
+        th.serveReportStatus(w, r)
+        return
+    }
+
+    w.Header().Set("Vary", "Accept")
+    mediaType, ok := negotiateContentType(r.Header.Get("Accept"), agentMediaTypes)
+    if !ok {
+        writeProblem(w, http.StatusNotAcceptable, Errorf(codes.InvalidArgument, "supported media types: %s", strings.Join(agentMediaTypes, ", ")))
+        return
+    }
+
+    out, err := marshalAgentData(mediaType, generateObject())
+    if err != nil {
+        WriteError(w, Errorf(codes.Internal, "%v", err))
+        return
+    }
+    w.Header().Set("Content-Type", mediaType)
     w.Write(out)
 }
 
+func (th *AgentHandler) serveReportStatus(w http.ResponseWriter, r *http.Request) {
+    mediaType := jsonMediaType
+    if contentType := r.Header.Get("Content-Type"); contentType != "" {
+        mediaType = strings.TrimSpace(strings.Split(contentType, ";")[0])
+    }
+
+    if !isAgentMediaType(mediaType) {
+        writeProblem(w, http.StatusUnsupportedMediaType, Errorf(codes.InvalidArgument, "supported media types: %s", strings.Join(agentMediaTypes, ", ")))
+        return
+    }
+
+    body, err := ioutil.ReadAll(r.Body)
+    if err != nil {
+        WriteError(w, Errorf(codes.InvalidArgument, "%v", err))
+        return
+    }
+    var arg AgentData
+    if err := unmarshalAgentData(mediaType, body, &arg); err != nil {
+        WriteError(w, Errorf(codes.InvalidArgument, "%v", err))
+        return
+    }
+
+    var reply StatusAck
+    if err := th.ReportStatus(&arg, &reply); err != nil {
+        WriteError(w, err)
+        return
+    }
+    out, err := json.Marshal(reply)
+    if err != nil {
+        WriteError(w, Errorf(codes.Internal, "%v", err))
+        return
+    }
+    w.Header().Set("Content-Type", jsonMediaType)
+    w.Write(out)
+}
+
+var registerArithOnce sync.Once
+
+// registerDefaultArith registers Arith on rpc.DefaultServer, which the TCP,
+// JSON and HTTP RPC servers share, the first time one of them starts.
+func registerDefaultArith() {
+    registerArithOnce.Do(func() {
+        err := registerArith(rpc.DefaultServer, new(Arith))
+        if err != nil {
+            panic(err)
+        }
+    })
+}
+
 func startTCPRPCServer() {
     if tcpHandler != nil {
         return
     }
     tcpHandler = new(AgentHandler)
     rpc.Register(tcpHandler)
+    registerDefaultArith()
 
     tcpAddr, err := net.ResolveTCPAddr("tcp", ":8081")
     if err != nil {
@@ -96,6 +181,7 @@ func startJSONRPCServer() {
     }
     jsonHandler = new(AgentHandler)
     rpc.Register(jsonHandler)
+    registerDefaultArith()
 
     tcpAddr, err := net.ResolveTCPAddr("tcp", ":8082")
     if err != nil {
@@ -127,6 +213,8 @@ func startGRPCServer() {
         panic(err)
     }
     pb.RegisterAgentServer(grpcServer, grpcHandler)
+    pb.RegisterArithServer(grpcServer, NewArithGRPCServer(new(Arith)))
+    grpcHealthServer = registerIntrospection(grpcServer)
     go func() {
         err = grpcServer.Serve(listener)
         if err != nil {
@@ -141,10 +229,16 @@ func startHTTPRPCServer() {
     }
     httpHandler = new(AgentHandler)
     rpc.Register(httpHandler)
+    registerDefaultArith()
     rpc.HandleHTTP()
 
+    listener, err := net.Listen("tcp", ":8083")
+    if err != nil {
+        panic(err)
+    }
+
     go func() {
-        err := http.ListenAndServe(":8083", nil)
+        err := http.Serve(listener, nil)
         if err != nil {
             fmt.Println(err.Error())
         }
@@ -156,8 +250,14 @@ func startHTTPServer() {
         return
     }
 
+    handler := &AgentHandler{}
+    mux := http.NewServeMux()
+    mux.Handle("/", handler)
+    mux.Handle(AgentWebPath, NewAgentWebHandler(handler))
+    mux.Handle(ArithPath, NewArithHTTPHandler(new(Arith)))
+
     httpServer = &http.Server{
-        Handler: &AgentHandler{},
+        Handler: DeadlineHandler(mux),
     }
 
     listener, err := net.Listen("tcp", ":8080")
@@ -178,8 +278,14 @@ func startHTTPSServer() {
         return
     }
 
+    cert, err := SelfSignedCertificate("127.0.0.1", "localhost")
+    if err != nil {
+        panic(err)
+    }
+
     httpsServer = &http.Server{
-        Handler: &AgentHandler{},
+        Handler:   &AgentHandler{},
+        TLSConfig: &tls.Config{Certificates: []tls.Certificate{cert}},
     }
 
     listener, err := net.Listen("tcp", ":8443")
@@ -188,7 +294,7 @@ func startHTTPSServer() {
     }
 
     go func() {
-        err := httpServer.ServeTLS(listener, "https-server.crt", "https-server.key")
+        err := httpsServer.ServeTLS(listener, "", "")
         if err != nil {
             panic(err)
         }
@@ -225,7 +331,7 @@ func BenchmarkHTTPRPC(b *testing.B) {
     b.ResetTimer()
     var reply AgentData
     for n := 0; n < b.N; n++ {
-        err := client.Call("AgentHandler.Serve", string(n), &reply)
+        err := client.Call("AgentHandler.Serve", strconv.Itoa(n), &reply)
         if err != nil {
             panic(err)
         }
@@ -241,7 +347,7 @@ func BenchmarkTCPRPC(b *testing.B) {
     b.ResetTimer()
     var reply AgentData
     for n := 0; n < b.N; n++ {
-      err := client.Call("AgentHandler.Serve", string(n), &reply)
+      err := client.Call("AgentHandler.Serve", strconv.Itoa(n), &reply)
       if err != nil {
           panic(err)
       }
@@ -257,7 +363,7 @@ func BenchmarkJSONRPC(b *testing.B) {
     b.ResetTimer()
     var reply AgentData
     for n := 0; n < b.N; n++ {
-        err := client.Call("AgentHandler.Serve", string(n), &reply)
+        err := client.Call("AgentHandler.Serve", strconv.Itoa(n), &reply)
         if err != nil {
             panic(err)
         }
@@ -272,7 +378,7 @@ func BenchmarkGPRPC(b *testing.B) {
     client := pb.NewAgentClient(conn)
     b.ResetTimer()
     for n := 0; n < b.N; n++ {
-        _, err := client.ServeAgentProto(context.Background(), &pb.AgentRequest{Data: string(n)})
+        _, err := client.ServeAgentProto(context.Background(), &pb.AgentRequest{Data: strconv.Itoa(n)})
         if err != nil {
             panic(err)
         }
@@ -305,18 +411,18 @@ func BenchmarkHTTPNoKeepAlive(b *testing.B) {
     }
 }
 
-//func BenchmarkHTTPSNoKeepAlive(b *testing.B) {
-//    startHTTPSServer()
-//
-//    client := &http.Client{
-//        Transport: &http.Transport{
-//            DisableKeepAlives: true,
-//            TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
-//        },
-//    }
-//
-//    b.ResetTimer()
-//    for n := 0; n < b.N; n++ {
-//        sendRequest(client, "https://127.0.0.1:8443/")
-//    }
-//}
+func BenchmarkHTTPSNoKeepAlive(b *testing.B) {
+    startHTTPSServer()
+
+    client := &http.Client{
+        Transport: &http.Transport{
+            DisableKeepAlives: true,
+            TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+        },
+    }
+
+    b.ResetTimer()
+    for n := 0; n < b.N; n++ {
+        sendRequest(client, "https://127.0.0.1:8443/")
+    }
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/rpc"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// HTTPError is returned by CheckHTTPResponse for responses with an error
+// status.
+type HTTPError struct {
+	StatusCode  int
+	ContentType string
+	Body        string
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("http: %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Body)
+}
+
+// CheckHTTPResponse returns an *HTTPError carrying the body of res if its
+// status is 400 or above. The body is consumed and closed in that case.
+func CheckHTTPResponse(res *http.Response) error {
+	if res.StatusCode < http.StatusBadRequest {
+		return nil
+	}
+	body, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return err
+	}
+	return &HTTPError{
+		StatusCode:  res.StatusCode,
+		ContentType: res.Header.Get("Content-Type"),
+		Body:        strings.TrimSpace(string(body)),
+	}
+}
+
+// CallError is the transport-independent view of a failed call, the client
+// side counterpart of StatusError. Code uses the gRPC vocabulary since it is
+// the richest of the transports; Err keeps the error as returned by the
+// transport's client.
+type CallError struct {
+	Code    codes.Code
+	Message string
+	Details map[string]string
+	Err     error
+}
+
+func newCallError(e *StatusError, err error) *CallError {
+	return &CallError{Code: e.Code, Message: e.Message, Details: e.Details, Err: err}
+}
+
+func (e *CallError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+func (e *CallError) Unwrap() error {
+	return e.Err
+}
+
+// ToCallError classifies an error returned by a net/rpc, JSON-RPC, HTTP or
+// gRPC client. It returns nil for a nil error. A StatusError returned by the
+// handler is recovered with its code and details on every transport; other
+// application errors yield codes.Unknown where the transport does not carry
+// a code, such as net/rpc.
+func ToCallError(err error) *CallError {
+	if err == nil {
+		return nil
+	}
+	var callErr *CallError
+	if errors.As(err, &callErr) {
+		return callErr
+	}
+
+	var (
+		statusErr  *StatusError
+		serverErr  rpc.ServerError
+		jsonrpcErr *JSONRPCError
+		httpErr    *HTTPError
+	)
+	switch {
+	case errors.As(err, &statusErr):
+		return newCallError(statusErr, err)
+	case errors.As(err, &serverErr):
+		msg := string(serverErr)
+		if e, ok := parseStatusError(msg); ok {
+			return newCallError(e, err)
+		}
+		code := codes.Unknown
+		if strings.HasPrefix(msg, "rpc: can't find ") {
+			code = codes.Unimplemented
+		}
+		return &CallError{Code: code, Message: msg, Err: err}
+	case errors.As(err, &jsonrpcErr):
+		if e, ok := statusErrorFromJSONRPC(jsonrpcErr); ok {
+			return newCallError(e, err)
+		}
+		return &CallError{Code: codeFromJSONRPC(jsonrpcErr.Code), Message: jsonrpcErr.Message, Err: err}
+	case errors.As(err, &httpErr):
+		if strings.HasPrefix(httpErr.ContentType, problemMediaType) {
+			if e, ok := statusErrorFromProblem([]byte(httpErr.Body)); ok {
+				return newCallError(e, err)
+			}
+		}
+		return &CallError{Code: CodeFromHTTPStatus(httpErr.StatusCode), Message: httpErr.Body, Err: err}
+	case err == rpc.ErrShutdown || err == io.ErrUnexpectedEOF || err == io.EOF:
+		return &CallError{Code: codes.Unavailable, Message: err.Error(), Err: err}
+	}
+	if s, ok := status.FromError(err); ok {
+		return newCallError(statusErrorFromGRPC(s), err)
+	}
+	return &CallError{Code: codes.Unknown, Message: err.Error(), Err: err}
+}
+
+func codeFromJSONRPC(code int) codes.Code {
+	switch code {
+	case JSONRPCParseError, JSONRPCInvalidRequest, JSONRPCInvalidParams:
+		return codes.InvalidArgument
+	case JSONRPCMethodNotFound:
+		return codes.Unimplemented
+	case JSONRPCInternalError:
+		return codes.Internal
+	}
+	return codes.Unknown
+}
+
+// CodeFromHTTPStatus is the inverse of HTTPStatusFromCode. Statuses that
+// several codes share map to the most general of them.
+func CodeFromHTTPStatus(statusCode int) codes.Code {
+	switch statusCode {
+	case http.StatusOK:
+		return codes.OK
+	case 499:
+		return codes.Canceled
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusGatewayTimeout:
+		return codes.DeadlineExceeded
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.Aborted
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case http.StatusNotImplemented:
+		return codes.Unimplemented
+	case http.StatusServiceUnavailable:
+		return codes.Unavailable
+	case http.StatusInternalServerError:
+		return codes.Internal
+	}
+	return codes.Unknown
+}
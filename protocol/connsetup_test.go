@@ -0,0 +1,260 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"testing"
+	"time"
+
+	pb "github.com/evaluate_serde_protocol/protocol/agent"
+	"google.golang.org/grpc"
+)
+
+// setupTimes splits the cost of establishing a connection and making the
+// first call on it.
+type setupTimes struct {
+	// connect is the TCP three-way handshake.
+	connect time.Duration
+	// handshake is any protocol-level negotiation before the first call can
+	// be sent: the HTTP CONNECT of HTTP-RPC, the HTTP/2 preface and settings
+	// exchange of gRPC, or the TLS handshake of HTTPS.
+	handshake time.Duration
+	// firstCall runs from the end of the handshake until the first response
+	// has been read, including one-off costs such as gob type descriptors.
+	firstCall time.Duration
+}
+
+// setupTransport starts a server for one transport on listener and returns
+// a function that opens a new client connection, makes one call on it and
+// closes it again.
+type setupTransport struct {
+	name  string
+	start func(listener net.Listener) (establish func() setupTimes, stop func())
+}
+
+// rpcSetup dials a net/rpc style server and makes one call. handshake, if
+// not nil, turns the raw connection into a client; otherwise newClient does
+// so without any exchange on the wire.
+func rpcSetup(address string, handshake func(net.Conn) (*rpc.Client, error), newClient func(io.ReadWriteCloser) *rpc.Client) setupTimes {
+	var times setupTimes
+	start := time.Now()
+	conn, err := net.Dial("tcp", address)
+	if err != nil {
+		panic(err)
+	}
+	connected := time.Now()
+	times.connect = connected.Sub(start)
+
+	var client *rpc.Client
+	if handshake != nil {
+		client, err = handshake(conn)
+		if err != nil {
+			panic(err)
+		}
+	} else {
+		client = newClient(conn)
+	}
+	handshaken := time.Now()
+	times.handshake = handshaken.Sub(connected)
+
+	rpcCaller(client)(0)
+	times.firstCall = time.Since(handshaken)
+	client.Close()
+	return times
+}
+
+// httpSetup makes one request over a new connection and splits its timing
+// with an httptrace.ClientTrace.
+func httpSetup(client *http.Client, url string) setupTimes {
+	var connectStart, connectDone, tlsStart, tlsDone time.Time
+	trace := &httptrace.ClientTrace{
+		ConnectStart:      func(string, string) { connectStart = time.Now() },
+		ConnectDone:       func(string, string, error) { connectDone = time.Now() },
+		TLSHandshakeStart: func() { tlsStart = time.Now() },
+		TLSHandshakeDone:  func(tls.ConnectionState, error) { tlsDone = time.Now() },
+	}
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		panic(err)
+	}
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+
+	res, err := client.Do(req)
+	if err != nil {
+		panic(err)
+	}
+	_, err = ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		panic(err)
+	}
+	if res.StatusCode != http.StatusOK {
+		panic("request failed")
+	}
+	end := time.Now()
+
+	if connectDone.IsZero() {
+		panic("request reused a connection")
+	}
+	times := setupTimes{connect: connectDone.Sub(connectStart)}
+	handshaken := connectDone
+	if !tlsDone.IsZero() {
+		times.handshake = tlsDone.Sub(tlsStart)
+		handshaken = tlsDone
+	}
+	times.firstCall = end.Sub(handshaken)
+	return times
+}
+
+func httpSetupTransport(listener net.Listener, transport *http.Transport, scheme string) (func() setupTimes, func()) {
+	server := &http.Server{Handler: new(AgentHandler)}
+	go server.Serve(listener)
+
+	client := &http.Client{Transport: transport}
+	url := scheme + "://" + listener.Addr().String() + "/"
+	return func() setupTimes {
+			return httpSetup(client, url)
+		}, func() {
+			transport.CloseIdleConnections()
+			server.Close()
+		}
+}
+
+var setupTransports = []setupTransport{
+	{"TCPRPC", func(listener net.Listener) (func() setupTimes, func()) {
+		go acceptLoop(listener, newAgentRPCServer().ServeConn)
+		return func() setupTimes {
+			return rpcSetup(listener.Addr().String(), nil, rpc.NewClient)
+		}, func() { listener.Close() }
+	}},
+	{"JSONRPC", func(listener net.Listener) (func() setupTimes, func()) {
+		server := newAgentRPCServer()
+		go acceptLoop(listener, func(conn io.ReadWriteCloser) {
+			server.ServeCodec(jsonrpc.NewServerCodec(conn))
+		})
+		return func() setupTimes {
+			return rpcSetup(listener.Addr().String(), nil, jsonrpc.NewClient)
+		}, func() { listener.Close() }
+	}},
+	{"HTTPRPC", func(listener net.Listener) (func() setupTimes, func()) {
+		go http.Serve(listener, newAgentRPCServer())
+		return func() setupTimes {
+			return rpcSetup(listener.Addr().String(), dialHTTPRPC, nil)
+		}, func() { listener.Close() }
+	}},
+	{"GRPC", func(listener net.Listener) (func() setupTimes, func()) {
+		server := grpc.NewServer()
+		pb.RegisterAgentServer(server, new(AgentHandler))
+		go server.Serve(listener)
+		return func() setupTimes {
+			var times setupTimes
+			start := time.Now()
+			conn, err := net.Dial("tcp", listener.Addr().String())
+			if err != nil {
+				panic(err)
+			}
+			connected := time.Now()
+			times.connect = connected.Sub(start)
+
+			// Hand the already connected socket to gRPC, so that a
+			// blocking dial only covers the HTTP/2 preface and settings
+			// exchange.
+			used := false
+			cc, err := grpc.Dial(listener.Addr().String(), grpc.WithInsecure(), grpc.WithBlock(),
+				grpc.WithContextDialer(func(ctx context.Context, address string) (net.Conn, error) {
+					if used {
+						return nil, errors.New("connection already used")
+					}
+					used = true
+					return conn, nil
+				}))
+			if err != nil {
+				panic(err)
+			}
+			handshaken := time.Now()
+			times.handshake = handshaken.Sub(connected)
+
+			_, err = pb.NewAgentClient(cc).ServeAgentProto(context.Background(), &pb.AgentRequest{Data: "0"})
+			if err != nil {
+				panic(err)
+			}
+			times.firstCall = time.Since(handshaken)
+			cc.Close()
+			return times
+		}, server.Stop
+	}},
+	{"HTTP", func(listener net.Listener) (func() setupTimes, func()) {
+		return httpSetupTransport(listener, &http.Transport{DisableKeepAlives: true}, "http")
+	}},
+	{"HTTPS", func(listener net.Listener) (func() setupTimes, func()) {
+		cert, err := SelfSignedCertificate("127.0.0.1")
+		if err != nil {
+			panic(err)
+		}
+		listener = tls.NewListener(listener, &tls.Config{Certificates: []tls.Certificate{cert}})
+		return httpSetupTransport(listener, &http.Transport{
+			DisableKeepAlives: true,
+			TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+		}, "https")
+	}},
+}
+
+func TestConnectionSetup(t *testing.T) {
+	for _, transport := range setupTransports {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		establish, stop := transport.start(listener)
+		times := establish()
+		stop()
+
+		if times.connect <= 0 || times.firstCall <= 0 {
+			t.Errorf("%s: connect %s, first call %s", transport.name, times.connect, times.firstCall)
+		}
+		switch transport.name {
+		case "HTTPRPC", "GRPC", "HTTPS":
+			if times.handshake <= 0 {
+				t.Errorf("%s: no handshake time recorded", transport.name)
+			}
+		}
+	}
+}
+
+// BenchmarkConnectionSetup opens a new connection and makes one call on it
+// per iteration. Besides the total it reports the mean time spent in the TCP
+// connect, the protocol handshake and the first call.
+func BenchmarkConnectionSetup(b *testing.B) {
+	for _, transport := range setupTransports {
+		b.Run(transport.name, func(b *testing.B) {
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				panic(err)
+			}
+			establish, stop := transport.start(listener)
+			defer stop()
+
+			var total setupTimes
+			b.ResetTimer()
+			for n := 0; n < b.N; n++ {
+				times := establish()
+				total.connect += times.connect
+				total.handshake += times.handshake
+				total.firstCall += times.firstCall
+			}
+			b.StopTimer()
+
+			b.ReportMetric(float64(total.connect)/float64(b.N), "connect-ns/op")
+			b.ReportMetric(float64(total.handshake)/float64(b.N), "handshake-ns/op")
+			b.ReportMetric(float64(total.firstCall)/float64(b.N), "first-call-ns/op")
+		})
+	}
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"testing"
+
+	pb "github.com/evaluate_serde_protocol/protocol/agent"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	jsonMediaType      = "application/json"
+	protobufMediaType  = "application/x-protobuf"
+	gobMediaType       = "application/x-gob"
+	msgpackMediaType   = "application/msgpack"
+	xMsgpackMediaType  = "application/x-msgpack"
+	protojsonMediaType = "application/x-protojson"
+)
+
+// agentMediaTypes lists the representations AgentHandler.ServeHTTP can
+// produce, in order of preference.
+var agentMediaTypes = []string{
+	jsonMediaType,
+	protobufMediaType,
+	gobMediaType,
+	msgpackMediaType,
+	xMsgpackMediaType,
+	protojsonMediaType,
+}
+
+func isAgentMediaType(mediaType string) bool {
+	for _, t := range agentMediaTypes {
+		if t == mediaType {
+			return true
+		}
+	}
+	return false
+}
+
+func toAgentProto(obj *AgentData) *pb.AgentProto {
+	return &pb.AgentProto{
+		Hostname:  obj.Hostname,
+		Status:    obj.Status,
+		Timestamp: obj.Timestamp,
+		Lsns:      obj.Lsns,
+	}
+}
+
+func fromAgentProto(in *pb.AgentProto) *AgentData {
+	return &AgentData{
+		Hostname:  in.Hostname,
+		Status:    in.Status,
+		Timestamp: in.Timestamp,
+		Lsns:      in.Lsns,
+	}
+}
+
+// MarshalMsgpack encodes obj as a MessagePack map keyed by the JSON names.
+func (obj *AgentData) MarshalMsgpack() []byte {
+	b := make([]byte, 0, 64)
+	b = appendMsgpackMapHeader(b, 4)
+	b = appendMsgpackString(b, "hostname")
+	b = appendMsgpackString(b, obj.Hostname)
+	b = appendMsgpackString(b, "status")
+	b = appendMsgpackString(b, obj.Status)
+	b = appendMsgpackString(b, "timestamp")
+	b = appendMsgpackInt(b, obj.Timestamp)
+	b = appendMsgpackString(b, "lsns")
+	b = appendMsgpackArrayHeader(b, len(obj.Lsns))
+	for _, lsn := range obj.Lsns {
+		b = appendMsgpackString(b, lsn)
+	}
+	return b
+}
+
+// UnmarshalMsgpack decodes a map written by MarshalMsgpack.
+func (obj *AgentData) UnmarshalMsgpack(b []byte) error {
+	r := &msgpackReader{b: b}
+	n, err := r.readMapHeader()
+	if err != nil {
+		return err
+	}
+	for i := 0; i < n; i++ {
+		key, err := r.readString()
+		if err != nil {
+			return err
+		}
+		switch key {
+		case "hostname":
+			obj.Hostname, err = r.readString()
+		case "status":
+			obj.Status, err = r.readString()
+		case "timestamp":
+			obj.Timestamp, err = r.readInt()
+		case "lsns":
+			var count int
+			count, err = r.readArrayHeader()
+			if err != nil {
+				return err
+			}
+			obj.Lsns = make([]string, count)
+			for j := range obj.Lsns {
+				if obj.Lsns[j], err = r.readString(); err != nil {
+					return err
+				}
+			}
+		default:
+			err = fmt.Errorf("msgpack: unknown field %q", key)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func marshalAgentData(mediaType string, obj *AgentData) ([]byte, error) {
+	switch mediaType {
+	case jsonMediaType:
+		return json.Marshal(obj)
+	case protobufMediaType:
+		return proto.Marshal(toAgentProto(obj))
+	case gobMediaType:
+		var buf bytes.Buffer
+		err := gob.NewEncoder(&buf).Encode(obj)
+		return buf.Bytes(), err
+	case msgpackMediaType, xMsgpackMediaType:
+		return obj.MarshalMsgpack(), nil
+	case protojsonMediaType:
+		return protojson.Marshal(toAgentProto(obj))
+	}
+	return nil, fmt.Errorf("unsupported media type %q", mediaType)
+}
+
+func unmarshalAgentData(mediaType string, b []byte, obj *AgentData) error {
+	switch mediaType {
+	case jsonMediaType:
+		return json.Unmarshal(b, obj)
+	case protobufMediaType, protojsonMediaType:
+		in := &pb.AgentProto{}
+		var err error
+		if mediaType == protobufMediaType {
+			err = proto.Unmarshal(b, in)
+		} else {
+			err = protojson.Unmarshal(b, in)
+		}
+		if err != nil {
+			return err
+		}
+		*obj = *fromAgentProto(in)
+		return nil
+	case gobMediaType:
+		return gob.NewDecoder(bytes.NewReader(b)).Decode(obj)
+	case msgpackMediaType, xMsgpackMediaType:
+		return obj.UnmarshalMsgpack(b)
+	}
+	return fmt.Errorf("unsupported media type %q", mediaType)
+}
+
+func getWithAccept(client *http.Client, accept string) (*http.Response, []byte) {
+	req, err := http.NewRequest(http.MethodGet, "http://127.0.0.1:8080/", nil)
+	if err != nil {
+		panic(err)
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		panic(err)
+	}
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		panic(err)
+	}
+	err = res.Body.Close()
+	if err != nil {
+		panic(err)
+	}
+	return res, body
+}
+
+func TestNegotiateContentType(t *testing.T) {
+	for _, tc := range []struct {
+		accept, want string
+	}{
+		{"", jsonMediaType},
+		{"*/*", jsonMediaType},
+		{"application/*", jsonMediaType},
+		{"application/x-gob", gobMediaType},
+		{"text/html, application/x-protobuf;q=0.9, */*;q=0.1", protobufMediaType},
+		{"application/json;q=0.5, application/msgpack", msgpackMediaType},
+		{"application/json;q=0, */*", protobufMediaType},
+		{"application/*;q=0.5, application/x-protojson", protojsonMediaType},
+	} {
+		got, ok := negotiateContentType(tc.accept, agentMediaTypes)
+		if !ok || got != tc.want {
+			t.Errorf("negotiateContentType(%q) = %q, %v; want %q", tc.accept, got, ok, tc.want)
+		}
+	}
+
+	for _, accept := range []string{"text/html", "application/json;q=0", "image/*"} {
+		if got, ok := negotiateContentType(accept, agentMediaTypes); ok {
+			t.Errorf("negotiateContentType(%q) = %q, want no match", accept, got)
+		}
+	}
+}
+
+func TestServeHTTPContentNegotiation(t *testing.T) {
+	startHTTPServer()
+
+	for _, mediaType := range agentMediaTypes {
+		res, body := getWithAccept(http.DefaultClient, mediaType)
+		if res.StatusCode != http.StatusOK {
+			t.Fatalf("%s: status %d", mediaType, res.StatusCode)
+		}
+		if got := res.Header.Get("Content-Type"); got != mediaType {
+			t.Errorf("%s: Content-Type %q", mediaType, got)
+		}
+		obj := &AgentData{}
+		if err := unmarshalAgentData(mediaType, body, obj); err != nil {
+			t.Fatalf("%s: %v", mediaType, err)
+		}
+		if !reflect.DeepEqual(obj, generateObject()) {
+			t.Errorf("%s: got %+v, want %+v", mediaType, obj, generateObject())
+		}
+	}
+
+	res, _ := getWithAccept(http.DefaultClient, "text/html")
+	if res.StatusCode != http.StatusNotAcceptable {
+		t.Errorf("text/html: got status %d, want %d", res.StatusCode, http.StatusNotAcceptable)
+	}
+}
+
+func BenchmarkHTTPMediaType(b *testing.B) {
+	startHTTPServer()
+
+	for _, mediaType := range agentMediaTypes {
+		b.Run(mediaType[len("application/"):], func(b *testing.B) {
+			client := &http.Client{}
+
+			b.ResetTimer()
+			var size int
+			for n := 0; n < b.N; n++ {
+				res, body := getWithAccept(client, mediaType)
+				if res.StatusCode != http.StatusOK {
+					panic("request failed")
+				}
+				size = len(body)
+			}
+			b.ReportMetric(float64(size), "body-bytes")
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+)
+
+// ByteCounter tallies the bytes read from and written to every connection
+// wrapped by it, so that the full on-the-wire cost of a protocol can be
+// measured, framing and handshakes included. It is safe for concurrent use.
+type ByteCounter struct {
+	read    int64
+	written int64
+}
+
+// Read returns the number of bytes read so far.
+func (c *ByteCounter) Read() int64 {
+	return atomic.LoadInt64(&c.read)
+}
+
+// Written returns the number of bytes written so far.
+func (c *ByteCounter) Written() int64 {
+	return atomic.LoadInt64(&c.written)
+}
+
+// Reset sets both counts back to zero.
+func (c *ByteCounter) Reset() {
+	atomic.StoreInt64(&c.read, 0)
+	atomic.StoreInt64(&c.written, 0)
+}
+
+// Conn wraps conn so that its traffic is counted by c.
+func (c *ByteCounter) Conn(conn net.Conn) net.Conn {
+	return &countingConn{Conn: conn, counter: c}
+}
+
+// Listener wraps l so that the traffic of every accepted connection is
+// counted by c.
+func (c *ByteCounter) Listener(l net.Listener) net.Listener {
+	return &countingListener{Listener: l, counter: c}
+}
+
+// DialContext dials address and wraps the connection with Conn. Its
+// signature matches http.Transport.DialContext.
+func (c *ByteCounter) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+	return c.Conn(conn), nil
+}
+
+// Dial is DialContext without a context, matching net.Dial.
+func (c *ByteCounter) Dial(network, address string) (net.Conn, error) {
+	return c.DialContext(context.Background(), network, address)
+}
+
+type countingConn struct {
+	net.Conn
+	counter *ByteCounter
+}
+
+func (c *countingConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	atomic.AddInt64(&c.counter.read, int64(n))
+	return n, err
+}
+
+func (c *countingConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	atomic.AddInt64(&c.counter.written, int64(n))
+	return n, err
+}
+
+type countingListener struct {
+	net.Listener
+	counter *ByteCounter
+}
+
+func (l *countingListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return l.counter.Conn(conn), nil
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/rpc"
+	"reflect"
+	"time"
+
+	"google.golang.org/grpc/codes"
+)
+
+// TimeoutHeader carries the time left until the caller's deadline on HTTP
+// requests, formatted as a time.Duration, like grpc-timeout does for gRPC.
+const TimeoutHeader = "X-Request-Timeout"
+
+// contextError converts the error of a done context to a StatusError with
+// the code gRPC uses for it.
+func contextError(err error) *StatusError {
+	if err == context.DeadlineExceeded {
+		return Errorf(codes.DeadlineExceeded, "%v", err)
+	}
+	return Errorf(codes.Canceled, "%v", err)
+}
+
+// ContextClient adds context support to a net/rpc client, whatever its
+// codec. net/rpc has no way to tell the server a call was abandoned, so the
+// server keeps running it; the client stops waiting and discards the reply.
+type ContextClient struct {
+	*rpc.Client
+}
+
+// NewContextClient wraps client.
+func NewContextClient(client *rpc.Client) *ContextClient {
+	return &ContextClient{Client: client}
+}
+
+// CallContext is like Call but returns a StatusError with code
+// DeadlineExceeded or Canceled as soon as ctx is done. reply is only
+// written if the call completes in time.
+func (c *ContextClient) CallContext(ctx context.Context, serviceMethod string, args interface{}, reply interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return contextError(err)
+	}
+	if ctx.Done() == nil {
+		return c.Call(serviceMethod, args, reply)
+	}
+
+	// Decode into a private value so that a reply arriving after the call
+	// was abandoned cannot race with the caller's use of reply.
+	private := reflect.New(reflect.TypeOf(reply).Elem())
+	call := c.Go(serviceMethod, args, private.Interface(), make(chan *rpc.Call, 1))
+	select {
+	case <-call.Done:
+		if call.Error != nil {
+			return call.Error
+		}
+		reflect.ValueOf(reply).Elem().Set(private.Elem())
+		return nil
+	case <-ctx.Done():
+		return contextError(ctx.Err())
+	}
+}
+
+// DeadlineTransport is an http.RoundTripper that sends the deadline of each
+// request's context in the TimeoutHeader, so that the server can stop work
+// the client will no longer wait for, even when the connection stays open
+// through a proxy.
+type DeadlineTransport struct {
+	// Base is the transport used to send requests;
+	// http.DefaultTransport if nil.
+	Base http.RoundTripper
+}
+
+func (t *DeadlineTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	deadline, ok := req.Context().Deadline()
+	if !ok {
+		return base.RoundTrip(req)
+	}
+	// RoundTrippers must not modify the request they are given.
+	req = req.Clone(req.Context())
+	req.Header.Set(TimeoutHeader, time.Until(deadline).String())
+	return base.RoundTrip(req)
+}
+
+// DeadlineHandler applies the deadline in the TimeoutHeader of a request to
+// its context before calling h. Requests with a malformed header are
+// rejected.
+func DeadlineHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		value := r.Header.Get(TimeoutHeader)
+		if value == "" {
+			h.ServeHTTP(w, r)
+			return
+		}
+		timeout, err := time.ParseDuration(value)
+		if err != nil {
+			WriteError(w, Errorf(codes.InvalidArgument, "invalid %s: %v", TimeoutHeader, err).WithDetail("header", TimeoutHeader))
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		h.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,368 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"strconv"
+	"testing"
+	"time"
+
+	pb "github.com/evaluate_serde_protocol/protocol/agent"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// slowHandler serves AgentHandler.Serve over net/rpc, gRPC and HTTP after a
+// delay, returning early where the transport cancels the call's context.
+// Each finished call sends its context error, or nil, to done.
+type slowHandler struct {
+	pb.UnimplementedAgentServer
+	delay time.Duration
+	done  chan error
+}
+
+func newSlowHandler(delay time.Duration) *slowHandler {
+	return &slowHandler{delay: delay, done: make(chan error, 16)}
+}
+
+func (h *slowHandler) wait(ctx context.Context) error {
+	timer := time.NewTimer(h.delay)
+	defer timer.Stop()
+	var err error
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	h.done <- err
+	return err
+}
+
+func (h *slowHandler) Serve(arg *string, reply *AgentData) error {
+	h.wait(context.Background())
+	*reply = *generateObject()
+	return nil
+}
+
+func (h *slowHandler) ServeAgentProto(ctx context.Context, in *pb.AgentRequest) (*pb.AgentProto, error) {
+	if err := h.wait(ctx); err != nil {
+		return nil, contextError(err)
+	}
+	return toAgentProto(generateObject()), nil
+}
+
+func (h *slowHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := h.wait(r.Context()); err != nil {
+		WriteError(w, contextError(err))
+		return
+	}
+	out, _ := json.Marshal(generateObject())
+	w.Header().Set("Content-Type", jsonMediaType)
+	w.Write(out)
+}
+
+// waitDone returns the context error the slow handler finished its next call
+// with.
+func (h *slowHandler) waitDone(t *testing.T) error {
+	select {
+	case err := <-h.done:
+		return err
+	case <-time.After(5 * time.Second):
+		t.Fatal("slow handler did not return")
+		return nil
+	}
+}
+
+func listenLoopback(t *testing.T) net.Listener {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return listener
+}
+
+// contextCaller is implemented by ContextClient and JSONRPCClient.
+type contextCaller interface {
+	CallContext(ctx context.Context, method string, args, reply interface{}) error
+	Close() error
+}
+
+// contextClients serve the slow handler on a loopback listener and return
+// a client for it, which stops waiting when the call's context is done
+// while the server keeps running the call.
+var contextClients = []struct {
+	name  string
+	serve func(handler *slowHandler, listener net.Listener)
+	dial  func(address string) (contextCaller, error)
+}{
+	{"TCPRPC", func(handler *slowHandler, listener net.Listener) {
+		acceptLoop(listener, slowRPCServer(handler).ServeConn)
+	}, func(address string) (contextCaller, error) {
+		return dialContextClient(rpc.Dial("tcp", address))
+	}},
+	{"JSONRPC", func(handler *slowHandler, listener net.Listener) {
+		server := slowRPCServer(handler)
+		acceptLoop(listener, func(conn io.ReadWriteCloser) {
+			server.ServeCodec(jsonrpc.NewServerCodec(conn))
+		})
+	}, func(address string) (contextCaller, error) {
+		return dialContextClient(jsonrpc.Dial("tcp", address))
+	}},
+	{"HTTPRPC", func(handler *slowHandler, listener net.Listener) {
+		http.Serve(listener, slowRPCServer(handler))
+	}, func(address string) (contextCaller, error) {
+		return dialContextClient(rpc.DialHTTP("tcp", address))
+	}},
+	{"JSONRPC2", func(handler *slowHandler, listener net.Listener) {
+		slowJSONRPCServer(handler).Accept(listener)
+	}, func(address string) (contextCaller, error) {
+		return DialJSONRPC("tcp", address)
+	}},
+	{"JSONRPC2HTTP", func(handler *slowHandler, listener net.Listener) {
+		http.Serve(listener, slowJSONRPCServer(handler))
+	}, func(address string) (contextCaller, error) {
+		return NewJSONRPCHTTPClient(&http.Client{}, "http://"+address+"/"), nil
+	}},
+}
+
+func slowRPCServer(handler *slowHandler) *rpc.Server {
+	server := rpc.NewServer()
+	if err := server.RegisterName("AgentHandler", handler); err != nil {
+		panic(err)
+	}
+	return server
+}
+
+func slowJSONRPCServer(handler *slowHandler) *JSONRPCServer {
+	server := NewJSONRPCServer()
+	if err := server.RegisterName("AgentHandler", handler); err != nil {
+		panic(err)
+	}
+	return server
+}
+
+func dialContextClient(client *rpc.Client, err error) (contextCaller, error) {
+	if err != nil {
+		return nil, err
+	}
+	return NewContextClient(client), nil
+}
+
+func TestContextClient(t *testing.T) {
+	for _, codec := range contextClients {
+		handler := newSlowHandler(100 * time.Millisecond)
+		listener := listenLoopback(t)
+		go codec.serve(handler, listener)
+		client, err := codec.dial(listener.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var reply AgentData
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		start := time.Now()
+		err = client.CallContext(ctx, "AgentHandler.Serve", "0", &reply)
+		cancel()
+		if code := ToCallError(err).Code; code != codes.DeadlineExceeded {
+			t.Errorf("%s: timed out call returned %v, want %s", codec.name, err, codes.DeadlineExceeded)
+		}
+		if elapsed := time.Since(start); elapsed >= handler.delay {
+			t.Errorf("%s: timed out call took %s", codec.name, elapsed)
+		}
+
+		ctx, cancel = context.WithCancel(context.Background())
+		cancel()
+		err = client.CallContext(ctx, "AgentHandler.Serve", "1", &reply)
+		if code := ToCallError(err).Code; code != codes.Canceled {
+			t.Errorf("%s: cancelled call returned %v, want %s", codec.name, err, codes.Canceled)
+		}
+
+		// Neither net/rpc nor JSON-RPC 2.0 can cancel the server side, so
+		// the abandoned call still completes there and its late reply must
+		// be discarded.
+		if err := handler.waitDone(t); err != nil {
+			t.Errorf("%s: server call ended with %v", codec.name, err)
+		}
+		if reply.Hostname != "" {
+			t.Errorf("%s: abandoned call wrote reply %+v", codec.name, reply)
+		}
+
+		ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+		err = client.CallContext(ctx, "AgentHandler.Serve", "2", &reply)
+		cancel()
+		if err != nil || reply.Hostname != generateObject().Hostname {
+			t.Errorf("%s: call within deadline returned %+v, %v", codec.name, reply, err)
+		}
+		handler.waitDone(t)
+
+		client.Close()
+		listener.Close()
+	}
+}
+
+func TestGRPCDeadline(t *testing.T) {
+	handler := newSlowHandler(5 * time.Second)
+	addr, stop := startAgentGRPCServer(handler)
+	defer stop()
+	conn, err := grpc.Dial(addr, grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = pb.NewAgentClient(conn).ServeAgentProto(ctx, &pb.AgentRequest{})
+	if code := ToCallError(err).Code; code != codes.DeadlineExceeded {
+		t.Errorf("timed out call returned %v, want %s", err, codes.DeadlineExceeded)
+	}
+	if err := handler.waitDone(t); err == nil {
+		t.Error("server call was not cancelled")
+	}
+}
+
+func TestHTTPDeadline(t *testing.T) {
+	handler := newSlowHandler(5 * time.Second)
+	listener := listenLoopback(t)
+	server := &http.Server{Handler: DeadlineHandler(handler)}
+	go server.Serve(listener)
+	defer server.Close()
+	url := "http://" + listener.Addr().String() + "/"
+
+	// With a plain client, the header alone bounds the server's work.
+	req, _ := http.NewRequest(http.MethodGet, url, nil)
+	req.Header.Set(TimeoutHeader, (20 * time.Millisecond).String())
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = CheckHTTPResponse(res)
+	if code := ToCallError(err).Code; code != codes.DeadlineExceeded || res.StatusCode != http.StatusGatewayTimeout {
+		t.Errorf("request with %s returned %d %v", TimeoutHeader, res.StatusCode, err)
+	}
+	if err := handler.waitDone(t); err != context.DeadlineExceeded {
+		t.Errorf("server call ended with %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	// DeadlineTransport sends the header from the request's context.
+	client := &http.Client{Transport: &DeadlineTransport{}}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	req, _ = http.NewRequest(http.MethodGet, url, nil)
+	if _, err := client.Do(req.WithContext(ctx)); err == nil {
+		t.Error("request with a deadline succeeded")
+	}
+	if err := handler.waitDone(t); err == nil {
+		t.Error("server call was not cancelled")
+	}
+
+	req, _ = http.NewRequest(http.MethodGet, url, nil)
+	req.Header.Set(TimeoutHeader, "soon")
+	res, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code := ToCallError(CheckHTTPResponse(res)).Code; code != codes.InvalidArgument {
+		t.Errorf("malformed %s: got %s, want %s", TimeoutHeader, code, codes.InvalidArgument)
+	}
+}
+
+// BenchmarkDeadline measures the overhead of giving every call its own
+// deadline, against the same call without one.
+func BenchmarkDeadline(b *testing.B) {
+	startTCPRPCServer()
+	startJSONRPCServer()
+	startJSONRPC2Server()
+	startGRPCServer()
+	startHTTPServer()
+
+	run := func(b *testing.B, call func(ctx context.Context, n int)) {
+		b.Run("none", func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				call(context.Background(), n)
+			}
+		})
+		b.Run("deadline", func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+				call(ctx, n)
+				cancel()
+			}
+		})
+	}
+	rpcCall := func(client contextCaller) func(ctx context.Context, n int) {
+		var reply AgentData
+		return func(ctx context.Context, n int) {
+			err := client.CallContext(ctx, "AgentHandler.Serve", strconv.Itoa(n), &reply)
+			if err != nil {
+				panic(err)
+			}
+		}
+	}
+
+	b.Run("TCPRPC", func(b *testing.B) {
+		client, err := rpc.Dial("tcp", "127.0.0.1:8081")
+		if err != nil {
+			panic(err)
+		}
+		defer client.Close()
+		run(b, rpcCall(NewContextClient(client)))
+	})
+	b.Run("JSONRPC", func(b *testing.B) {
+		client, err := jsonrpc.Dial("tcp", "127.0.0.1:8082")
+		if err != nil {
+			panic(err)
+		}
+		defer client.Close()
+		run(b, rpcCall(NewContextClient(client)))
+	})
+	b.Run("JSONRPC2", func(b *testing.B) {
+		client, err := DialJSONRPC("tcp", "127.0.0.1:8086")
+		if err != nil {
+			panic(err)
+		}
+		defer client.Close()
+		run(b, rpcCall(client))
+	})
+	b.Run("JSONRPC2HTTP", func(b *testing.B) {
+		run(b, rpcCall(NewJSONRPCHTTPClient(&http.Client{Transport: &DeadlineTransport{Base: &http.Transport{}}}, jsonrpc2HTTPURL)))
+	})
+	b.Run("GRPC", func(b *testing.B) {
+		conn, err := grpc.Dial("127.0.0.1:8084", grpc.WithInsecure())
+		if err != nil {
+			panic(err)
+		}
+		defer conn.Close()
+		client := pb.NewAgentClient(conn)
+		run(b, func(ctx context.Context, n int) {
+			_, err := client.ServeAgentProto(ctx, &pb.AgentRequest{Data: strconv.Itoa(n)})
+			if err != nil {
+				panic(err)
+			}
+		})
+	})
+	b.Run("HTTP", func(b *testing.B) {
+		client := &http.Client{Transport: &DeadlineTransport{Base: &http.Transport{}}}
+		run(b, func(ctx context.Context, n int) {
+			req, err := http.NewRequest(http.MethodGet, "http://127.0.0.1:8080/", nil)
+			if err != nil {
+				panic(err)
+			}
+			res, err := client.Do(req.WithContext(ctx))
+			if err != nil {
+				panic(err)
+			}
+			if err := CheckHTTPResponse(res); err != nil {
+				panic(err)
+			}
+			io.Copy(ioutil.Discard, res.Body)
+			res.Body.Close()
+		})
+	})
+}
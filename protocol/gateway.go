@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+
+	pb "github.com/evaluate_serde_protocol/protocol/agent"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// AgentGatewayPath is the HTTP path the gateway serves ServeAgentProto on.
+const AgentGatewayPath = "/v1/agent"
+
+// AgentGatewayStatusPath is the HTTP path the gateway serves
+// ReportAgentProto on.
+const AgentGatewayStatusPath = "/v1/agent/status"
+
+// gatewayMaxBodySize is the largest POST body the gateway reads, the default
+// maximum receive size of a gRPC server.
+const gatewayMaxBodySize = 4 << 20
+
+// AgentGateway exposes the gRPC Agent service as HTTP/JSON endpoints.
+//
+// ServeAgentProto is served on AgentGatewayPath: GET requests take
+// AgentRequest.Data from the "data" query parameter, POST requests take a
+// protojson-encoded AgentRequest body. ReportAgentProto is served on
+// AgentGatewayStatusPath and takes a POST with a protojson-encoded
+// AgentProto body. Bodies are limited to gatewayMaxBodySize bytes, replies
+// are written as protojson and failures are written with WriteError, which
+// maps gRPC status codes to HTTP statuses.
+type AgentGateway struct {
+	client pb.AgentClient
+}
+
+// NewAgentGateway returns a gateway forwarding to client.
+func NewAgentGateway(client pb.AgentClient) *AgentGateway {
+	return &AgentGateway{client: client}
+}
+
+func (g *AgentGateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case AgentGatewayPath:
+		g.serveAgentProto(w, r)
+	case AgentGatewayStatusPath:
+		g.reportAgentProto(w, r)
+	default:
+		WriteError(w, Errorf(codes.NotFound, "unknown path %s", r.URL.Path))
+	}
+}
+
+func (g *AgentGateway) serveAgentProto(w http.ResponseWriter, r *http.Request) {
+	req := &pb.AgentRequest{}
+	switch r.Method {
+	case http.MethodGet:
+		req.Data = r.URL.Query().Get("data")
+	case http.MethodPost:
+		if !readProtoJSON(w, r, req) {
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	reply, err := g.client.ServeAgentProto(gatewayContext(r), req)
+	if err != nil {
+		WriteError(w, statusErrorFromGRPC(status.Convert(err)))
+		return
+	}
+	writeProtoJSON(w, http.StatusOK, reply)
+}
+
+func (g *AgentGateway) reportAgentProto(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	req := &pb.AgentProto{}
+	if !readProtoJSON(w, r, req) {
+		return
+	}
+
+	reply, err := g.client.ReportAgentProto(gatewayContext(r), req)
+	if err != nil {
+		WriteError(w, statusErrorFromGRPC(status.Convert(err)))
+		return
+	}
+	writeProtoJSON(w, http.StatusOK, reply)
+}
+
+// gatewayContext returns the context of r, forwarding its Authorization
+// header as gRPC metadata.
+func gatewayContext(r *http.Request) context.Context {
+	ctx := r.Context()
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, authorizationHeader, auth)
+	}
+	return ctx
+}
+
+// readProtoJSON decodes the protojson body of r into m. On failure it writes
+// the error and returns false.
+func readProtoJSON(w http.ResponseWriter, r *http.Request, m proto.Message) bool {
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, gatewayMaxBodySize))
+	if err != nil && len(body) == gatewayMaxBodySize {
+		WriteError(w, Errorf(codes.ResourceExhausted, "request body exceeds %d bytes", gatewayMaxBodySize))
+		return false
+	}
+	if err == nil {
+		err = protojson.Unmarshal(body, m)
+	}
+	if err != nil {
+		WriteError(w, Errorf(codes.InvalidArgument, "%v", err))
+		return false
+	}
+	return true
+}
+
+func writeProtoJSON(w http.ResponseWriter, code int, m proto.Message) {
+	out, err := protojson.Marshal(m)
+	if err != nil {
+		WriteError(w, Errorf(codes.Internal, "%v", err))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(out)
+}
+
+// HTTPStatusFromCode maps a gRPC status code to the equivalent HTTP status,
+// following the mapping documented in google/rpc/code.proto.
+func HTTPStatusFromCode(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		return 499
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	pb "github.com/evaluate_serde_protocol/protocol/agent"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+var gatewayServer *http.Server
+
+type failingAgent struct {
+	pb.UnimplementedAgentServer
+	code codes.Code
+}
+
+func (f failingAgent) ServeAgentProto(ctx context.Context, in *pb.AgentRequest) (*pb.AgentProto, error) {
+	return nil, status.Error(f.code, "failed: "+in.Data)
+}
+
+func startGatewayServer() {
+	if gatewayServer != nil {
+		return
+	}
+	startGRPCServer()
+
+	conn, err := grpc.Dial("127.0.0.1:8084", grpc.WithInsecure())
+	if err != nil {
+		panic(err)
+	}
+	gatewayServer = &http.Server{
+		Handler: NewAgentGateway(pb.NewAgentClient(conn)),
+	}
+
+	listener, err := net.Listen("tcp", ":8085")
+	if err != nil {
+		panic(err)
+	}
+
+	go func() {
+		err := gatewayServer.Serve(listener)
+		if err != nil {
+			panic(err)
+		}
+	}()
+}
+
+func TestGateway(t *testing.T) {
+	startGatewayServer()
+
+	for _, tc := range []struct {
+		method, body string
+	}{
+		{http.MethodGet, ""},
+		{http.MethodPost, `{"data":"1"}`},
+	} {
+		req, _ := http.NewRequest(tc.method, "http://127.0.0.1:8085"+AgentGatewayPath+"?data=1", strings.NewReader(tc.body))
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			t.Fatalf("%s: status %d: %s", tc.method, res.StatusCode, body)
+		}
+
+		reply := &pb.AgentProto{}
+		if err := protojson.Unmarshal(body, reply); err != nil {
+			t.Fatalf("%s: %v", tc.method, err)
+		}
+		want := generateObject()
+		if reply.Hostname != want.Hostname || reply.Timestamp != want.Timestamp || len(reply.Lsns) != len(want.Lsns) {
+			t.Fatalf("%s: got %v, want %v", tc.method, reply, want)
+		}
+	}
+}
+
+func TestGatewayReportStatus(t *testing.T) {
+	startGatewayServer()
+
+	obj := generateObject()
+	body, err := protojson.Marshal(toAgentProto(obj))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := http.Post("http://127.0.0.1:8085"+AgentGatewayStatusPath, "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("status %d: %s", res.StatusCode, out)
+	}
+	ack := &pb.StatusAck{}
+	if err := protojson.Unmarshal(out, ack); err != nil {
+		t.Fatal(err)
+	}
+	if ack.ReceivedLsns != int64(len(obj.Lsns)) {
+		t.Errorf("received %d lsns, want %d", ack.ReceivedLsns, len(obj.Lsns))
+	}
+
+	res, err = http.Get("http://127.0.0.1:8085" + AgentGatewayStatusPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("GET: got status %d, want %d", res.StatusCode, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestGatewayBodyTooLarge(t *testing.T) {
+	startGatewayServer()
+
+	body := `{"data":"` + strings.Repeat("x", gatewayMaxBodySize) + `"}`
+	res, err := http.Post("http://127.0.0.1:8085"+AgentGatewayPath, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	e, ok := statusErrorFromProblem(out)
+	if !ok || e.Code != codes.ResourceExhausted || res.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("got %d %s, want %v", res.StatusCode, out, codes.ResourceExhausted)
+	}
+}
+
+func TestGatewayErrors(t *testing.T) {
+	for _, tc := range []struct {
+		code   codes.Code
+		status int
+	}{
+		{codes.InvalidArgument, http.StatusBadRequest},
+		{codes.Unauthenticated, http.StatusUnauthorized},
+		{codes.NotFound, http.StatusNotFound},
+		{codes.Unavailable, http.StatusServiceUnavailable},
+		{codes.Internal, http.StatusInternalServerError},
+	} {
+		addr, stop := startAgentGRPCServer(&failingAgent{code: tc.code})
+		conn, err := grpc.Dial(addr, grpc.WithInsecure())
+		if err != nil {
+			t.Fatal(err)
+		}
+		server := httptest.NewServer(NewAgentGateway(pb.NewAgentClient(conn)))
+
+		res, err := http.Get(server.URL + AgentGatewayPath + "?data=x")
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		e, ok := statusErrorFromProblem(body)
+		if !ok || res.Header.Get("Content-Type") != problemMediaType {
+			t.Fatalf("%v: not a problem: %s %s", tc.code, res.Header.Get("Content-Type"), body)
+		}
+		if res.StatusCode != tc.status || e.Code != tc.code || e.Message != "failed: x" {
+			t.Errorf("%v: got %d %+v, want %d", tc.code, res.StatusCode, e, tc.status)
+		}
+
+		server.Close()
+		conn.Close()
+		stop()
+	}
+}
+
+func BenchmarkGateway(b *testing.B) {
+	startGatewayServer()
+
+	client := &http.Client{}
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		sendRequest(client, "http://127.0.0.1:8085"+AgentGatewayPath+"?data="+strconv.Itoa(n))
+	}
+}
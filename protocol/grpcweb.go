@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	pb "github.com/evaluate_serde_protocol/protocol/agent"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// AgentWebPath is the path browser clients post ServeAgentProto calls to,
+// following the gRPC "/package.Service/Method" convention.
+const AgentWebPath = "/agent.Agent/ServeAgentProto"
+
+const (
+	grpcWebContentType     = "application/grpc-web"
+	grpcWebTextContentType = "application/grpc-web-text"
+	connectProtoType       = "application/proto"
+	connectJSONType        = "application/json"
+
+	// grpcWebTrailerFlag marks a gRPC-Web frame carrying trailers instead of
+	// a message.
+	grpcWebTrailerFlag = 0x80
+
+	// grpcWebMaxMessageSize is the largest request message accepted on
+	// either protocol, the default maximum receive size of a gRPC server.
+	grpcWebMaxMessageSize = 4 << 20
+)
+
+// AgentWebHandler serves the Agent service over HTTP/1.1 for clients that
+// cannot speak raw gRPC. It accepts gRPC-Web requests, both binary
+// (application/grpc-web[+proto]) and base64 text (application/grpc-web-text),
+// and Connect-style unary POSTs with application/proto or application/json
+// bodies, and dispatches them to server.
+type AgentWebHandler struct {
+	server pb.AgentServer
+}
+
+// NewAgentWebHandler returns a handler dispatching to server.
+func NewAgentWebHandler(server pb.AgentServer) *AgentWebHandler {
+	return &AgentWebHandler{server: server}
+}
+
+func (h *AgentWebHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	if r.URL.Path != AgentWebPath {
+		http.NotFound(w, r)
+		return
+	}
+
+	contentType := r.Header.Get("Content-Type")
+	if i := strings.IndexByte(contentType, ';'); i >= 0 {
+		contentType = contentType[:i]
+	}
+	switch contentType {
+	case grpcWebContentType, grpcWebContentType + "+proto":
+		h.serveGRPCWeb(w, r, contentType, false)
+	case grpcWebTextContentType, grpcWebTextContentType + "+proto":
+		h.serveGRPCWeb(w, r, contentType, true)
+	case connectProtoType, connectJSONType:
+		h.serveConnect(w, r, contentType)
+	default:
+		http.Error(w, "unsupported content type "+contentType, http.StatusUnsupportedMediaType)
+	}
+}
+
+func (h *AgentWebHandler) serveGRPCWeb(w http.ResponseWriter, r *http.Request, contentType string, text bool) {
+	var body io.Reader = r.Body
+	if text {
+		body = base64.NewDecoder(base64.StdEncoding, body)
+	}
+
+	var out bytes.Buffer
+	reply, err := h.call(r, func(req *pb.AgentRequest) error {
+		msg, err := readGRPCWebMessage(body)
+		if err != nil {
+			return err
+		}
+		return proto.Unmarshal(msg, req)
+	})
+	s := toGRPCStatus(err)
+	if err == nil {
+		msg, err := proto.Marshal(reply)
+		if err != nil {
+			s = status.New(codes.Internal, err.Error())
+		} else {
+			writeGRPCWebFrame(&out, 0, msg)
+		}
+	}
+	trailer := fmt.Sprintf("grpc-status: %d\r\ngrpc-message: %s\r\n", s.Code(), url.PathEscape(s.Message()))
+	writeGRPCWebFrame(&out, grpcWebTrailerFlag, []byte(trailer))
+
+	w.Header().Set("Content-Type", contentType)
+	if text {
+		enc := base64.NewEncoder(base64.StdEncoding, w)
+		enc.Write(out.Bytes())
+		enc.Close()
+		return
+	}
+	w.Write(out.Bytes())
+}
+
+func (h *AgentWebHandler) serveConnect(w http.ResponseWriter, r *http.Request, contentType string) {
+	reply, err := h.call(r, func(req *pb.AgentRequest) error {
+		msg, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, grpcWebMaxMessageSize))
+		if err != nil && len(msg) == grpcWebMaxMessageSize {
+			return status.Errorf(codes.ResourceExhausted, "message exceeds the %d byte limit", grpcWebMaxMessageSize)
+		}
+		if err != nil {
+			return err
+		}
+		if contentType == connectJSONType {
+			return protojson.Unmarshal(msg, req)
+		}
+		return proto.Unmarshal(msg, req)
+	})
+	var out []byte
+	if err == nil {
+		if contentType == connectJSONType {
+			out, err = protojson.Marshal(reply)
+		} else {
+			out, err = proto.Marshal(reply)
+		}
+		if err != nil {
+			err = status.Error(codes.Internal, err.Error())
+		}
+	}
+	if err != nil {
+		writeConnectError(w, toGRPCStatus(err))
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Write(out)
+}
+
+// call decodes the request with decode and invokes ServeAgentProto. Decoding
+// failures are reported as codes.InvalidArgument unless decode returns a
+// status error of its own.
+func (h *AgentWebHandler) call(r *http.Request, decode func(*pb.AgentRequest) error) (*pb.AgentProto, error) {
+	req := &pb.AgentRequest{}
+	if err := decode(req); err != nil {
+		if _, ok := status.FromError(err); ok {
+			return nil, err
+		}
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return h.server.ServeAgentProto(r.Context(), req)
+}
+
+// readGRPCWebMessage reads a single uncompressed length-prefixed message.
+// Messages longer than grpcWebMaxMessageSize are rejected with
+// codes.ResourceExhausted before anything is allocated for them.
+func readGRPCWebMessage(r io.Reader) ([]byte, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+	if header[0] != 0 {
+		return nil, errors.New("compressed or trailer frames are not supported in requests")
+	}
+	n := binary.BigEndian.Uint32(header[1:])
+	if n > grpcWebMaxMessageSize {
+		return nil, status.Errorf(codes.ResourceExhausted, "message of %d bytes exceeds the %d byte limit", n, grpcWebMaxMessageSize)
+	}
+	msg := make([]byte, n)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+func writeGRPCWebFrame(buf *bytes.Buffer, flag byte, payload []byte) {
+	var header [5]byte
+	header[0] = flag
+	binary.BigEndian.PutUint32(header[1:], uint32(len(payload)))
+	buf.Write(header[:])
+	buf.Write(payload)
+}
+
+// connectCodes holds the Connect protocol names of the gRPC status codes.
+var connectCodes = map[codes.Code]string{
+	codes.Canceled:           "canceled",
+	codes.Unknown:            "unknown",
+	codes.InvalidArgument:    "invalid_argument",
+	codes.DeadlineExceeded:   "deadline_exceeded",
+	codes.NotFound:           "not_found",
+	codes.AlreadyExists:      "already_exists",
+	codes.PermissionDenied:   "permission_denied",
+	codes.ResourceExhausted:  "resource_exhausted",
+	codes.FailedPrecondition: "failed_precondition",
+	codes.Aborted:            "aborted",
+	codes.OutOfRange:         "out_of_range",
+	codes.Unimplemented:      "unimplemented",
+	codes.Internal:           "internal",
+	codes.Unavailable:        "unavailable",
+	codes.DataLoss:           "data_loss",
+	codes.Unauthenticated:    "unauthenticated",
+}
+
+// connectError is the JSON body of a failed Connect unary call.
+type connectError struct {
+	Code    string `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+func writeConnectError(w http.ResponseWriter, s *status.Status) {
+	code, ok := connectCodes[s.Code()]
+	if !ok {
+		code = connectCodes[codes.Unknown]
+	}
+	out, _ := json.Marshal(connectError{Code: code, Message: s.Message()})
+	w.Header().Set("Content-Type", connectJSONType)
+	w.WriteHeader(HTTPStatusFromCode(s.Code()))
+	w.Write(out)
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	pb "github.com/evaluate_serde_protocol/protocol/agent"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+const agentWebURL = "http://127.0.0.1:8080" + AgentWebPath
+
+func postWeb(client *http.Client, url, contentType string, body []byte) (*http.Response, []byte) {
+	res, err := client.Post(url, contentType, bytes.NewReader(body))
+	if err != nil {
+		panic(err)
+	}
+	out, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		panic(err)
+	}
+	err = res.Body.Close()
+	if err != nil {
+		panic(err)
+	}
+	return res, out
+}
+
+func grpcWebRequest(data string, text bool) []byte {
+	msg, err := proto.Marshal(&pb.AgentRequest{Data: data})
+	if err != nil {
+		panic(err)
+	}
+	var buf bytes.Buffer
+	writeGRPCWebFrame(&buf, 0, msg)
+	if text {
+		return []byte(base64.StdEncoding.EncodeToString(buf.Bytes()))
+	}
+	return buf.Bytes()
+}
+
+// parseGRPCWebResponse splits a gRPC-Web response body into its message
+// and trailer frames.
+func parseGRPCWebResponse(t *testing.T, body []byte, text bool) (*pb.AgentProto, string) {
+	if text {
+		decoded, err := base64.StdEncoding.DecodeString(string(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		body = decoded
+	}
+	var reply *pb.AgentProto
+	var trailer string
+	r := bytes.NewReader(body)
+	for r.Len() > 0 {
+		flag, _ := r.ReadByte()
+		r.UnreadByte()
+		if flag&grpcWebTrailerFlag != 0 {
+			rest, _ := ioutil.ReadAll(r)
+			trailer = string(rest[5:])
+			break
+		}
+		msg, err := readGRPCWebMessage(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reply = &pb.AgentProto{}
+		if err := proto.Unmarshal(msg, reply); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return reply, trailer
+}
+
+func TestGRPCWeb(t *testing.T) {
+	startHTTPServer()
+
+	for _, tc := range []struct {
+		contentType string
+		text        bool
+	}{
+		{"application/grpc-web", false},
+		{"application/grpc-web+proto", false},
+		{"application/grpc-web-text", true},
+	} {
+		res, body := postWeb(http.DefaultClient, agentWebURL, tc.contentType, grpcWebRequest("1", tc.text))
+		if res.StatusCode != http.StatusOK || res.Header.Get("Content-Type") != tc.contentType {
+			t.Fatalf("%s: got %d %s", tc.contentType, res.StatusCode, res.Header.Get("Content-Type"))
+		}
+		reply, trailer := parseGRPCWebResponse(t, body, tc.text)
+		if reply == nil || reply.Hostname != generateObject().Hostname {
+			t.Errorf("%s: unexpected reply %v", tc.contentType, reply)
+		}
+		if !strings.HasPrefix(trailer, "grpc-status: 0\r\n") {
+			t.Errorf("%s: unexpected trailer %q", tc.contentType, trailer)
+		}
+	}
+}
+
+func TestGRPCWebError(t *testing.T) {
+	server := httptest.NewServer(NewAgentWebHandler(&failingAgent{code: codes.NotFound}))
+	defer server.Close()
+
+	res, body := postWeb(http.DefaultClient, server.URL+AgentWebPath, grpcWebContentType, grpcWebRequest("x", false))
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("got status %d, want 200", res.StatusCode)
+	}
+	reply, trailer := parseGRPCWebResponse(t, body, false)
+	if reply != nil {
+		t.Errorf("unexpected reply %v", reply)
+	}
+	if want := "grpc-status: 5\r\ngrpc-message: failed:%20x\r\n"; trailer != want {
+		t.Errorf("trailer = %q, want %q", trailer, want)
+	}
+}
+
+func TestGRPCWebMessageTooLarge(t *testing.T) {
+	server := httptest.NewServer(NewAgentWebHandler(&failingAgent{code: codes.NotFound}))
+	defer server.Close()
+
+	// A frame header claiming a 4 GB message, with no message after it.
+	frame := []byte{0, 0xff, 0xff, 0xff, 0xff}
+	res, body := postWeb(http.DefaultClient, server.URL+AgentWebPath, grpcWebContentType, frame)
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("got status %d, want 200", res.StatusCode)
+	}
+	_, trailer := parseGRPCWebResponse(t, body, false)
+	if want := "grpc-status: " + strconv.Itoa(int(codes.ResourceExhausted)) + "\r\n"; !strings.HasPrefix(trailer, want) {
+		t.Errorf("trailer = %q, want prefix %q", trailer, want)
+	}
+}
+
+func TestConnect(t *testing.T) {
+	startHTTPServer()
+
+	req, _ := proto.Marshal(&pb.AgentRequest{Data: "1"})
+	res, body := postWeb(http.DefaultClient, agentWebURL, connectProtoType, req)
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("proto: status %d: %s", res.StatusCode, body)
+	}
+	reply := &pb.AgentProto{}
+	if err := proto.Unmarshal(body, reply); err != nil {
+		t.Fatal(err)
+	}
+	if reply.Hostname != generateObject().Hostname {
+		t.Errorf("proto: unexpected reply %v", reply)
+	}
+
+	res, body = postWeb(http.DefaultClient, agentWebURL, connectJSONType, []byte(`{"data":"1"}`))
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("json: status %d: %s", res.StatusCode, body)
+	}
+	reply = &pb.AgentProto{}
+	if err := protojson.Unmarshal(body, reply); err != nil {
+		t.Fatal(err)
+	}
+	if reply.Hostname != generateObject().Hostname {
+		t.Errorf("json: unexpected reply %v", reply)
+	}
+}
+
+func TestConnectError(t *testing.T) {
+	server := httptest.NewServer(NewAgentWebHandler(&failingAgent{code: codes.PermissionDenied}))
+	defer server.Close()
+
+	res, body := postWeb(http.DefaultClient, server.URL+AgentWebPath, connectJSONType, []byte(`{"data":"x"}`))
+	if res.StatusCode != http.StatusForbidden {
+		t.Fatalf("got status %d, want %d", res.StatusCode, http.StatusForbidden)
+	}
+	var cerr connectError
+	if err := json.Unmarshal(body, &cerr); err != nil {
+		t.Fatal(err)
+	}
+	if cerr.Code != "permission_denied" || cerr.Message != "failed: x" {
+		t.Errorf("unexpected error body %+v", cerr)
+	}
+
+	res, _ = postWeb(http.DefaultClient, server.URL+AgentWebPath, connectJSONType, []byte(`not json`))
+	if res.StatusCode != http.StatusBadRequest {
+		t.Errorf("malformed body: got status %d, want %d", res.StatusCode, http.StatusBadRequest)
+	}
+}
+
+// wrappingAgent fails with a wrapped *StatusError.
+type wrappingAgent struct {
+	pb.UnimplementedAgentServer
+}
+
+func (wrappingAgent) ServeAgentProto(ctx context.Context, in *pb.AgentRequest) (*pb.AgentProto, error) {
+	return nil, fmt.Errorf("serve %s: %w", in.Data, Errorf(codes.NotFound, "no agent %s", in.Data))
+}
+
+func TestConnectStatusError(t *testing.T) {
+	server := httptest.NewServer(NewAgentWebHandler(&wrappingAgent{}))
+	defer server.Close()
+
+	res, body := postWeb(http.DefaultClient, server.URL+AgentWebPath, connectJSONType, []byte(`{"data":"x"}`))
+	if res.StatusCode != http.StatusNotFound || res.Header.Get("Content-Type") != connectJSONType {
+		t.Fatalf("got status %d, Content-Type %q", res.StatusCode, res.Header.Get("Content-Type"))
+	}
+	var cerr connectError
+	if err := json.Unmarshal(body, &cerr); err != nil {
+		t.Fatal(err)
+	}
+	if cerr.Code != "not_found" || cerr.Message != "no agent x" {
+		t.Errorf("unexpected error body %+v", cerr)
+	}
+
+	res, body = postWeb(http.DefaultClient, server.URL+AgentWebPath, grpcWebContentType, grpcWebRequest("x", false))
+	_, trailer := parseGRPCWebResponse(t, body, false)
+	if want := "grpc-status: 5\r\ngrpc-message: no%20agent%20x\r\n"; trailer != want {
+		t.Errorf("gRPC-Web trailer = %q, want %q", trailer, want)
+	}
+}
+
+func TestConnectMessageTooLarge(t *testing.T) {
+	server := httptest.NewServer(NewAgentWebHandler(new(AgentHandler)))
+	defer server.Close()
+
+	body := make([]byte, grpcWebMaxMessageSize+1)
+	res, out := postWeb(http.DefaultClient, server.URL+AgentWebPath, connectProtoType, body)
+	if res.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("got status %d, want %d", res.StatusCode, http.StatusTooManyRequests)
+	}
+	var cerr connectError
+	if err := json.Unmarshal(out, &cerr); err != nil {
+		t.Fatal(err)
+	}
+	if cerr.Code != "resource_exhausted" {
+		t.Errorf("unexpected error body %+v", cerr)
+	}
+}
+
+func benchmarkWeb(b *testing.B, contentType string, body func(n int) []byte) {
+	startHTTPServer()
+
+	client := &http.Client{}
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		res, _ := postWeb(client, agentWebURL, contentType, body(n))
+		if res.StatusCode != http.StatusOK {
+			panic("request failed")
+		}
+	}
+}
+
+func BenchmarkGRPCWeb(b *testing.B) {
+	benchmarkWeb(b, grpcWebContentType, func(n int) []byte {
+		return grpcWebRequest(strconv.Itoa(n), false)
+	})
+}
+
+func BenchmarkGRPCWebText(b *testing.B) {
+	benchmarkWeb(b, grpcWebTextContentType, func(n int) []byte {
+		return grpcWebRequest(strconv.Itoa(n), true)
+	})
+}
+
+func BenchmarkConnectProto(b *testing.B) {
+	benchmarkWeb(b, connectProtoType, func(n int) []byte {
+		out, err := proto.Marshal(&pb.AgentRequest{Data: strconv.Itoa(n)})
+		if err != nil {
+			panic(err)
+		}
+		return out
+	})
+}
+
+func BenchmarkConnectJSON(b *testing.B) {
+	benchmarkWeb(b, connectJSONType, func(n int) []byte {
+		out, err := protojson.Marshal(&pb.AgentRequest{Data: strconv.Itoa(n)})
+		if err != nil {
+			panic(err)
+		}
+		return out
+	})
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+// registerIntrospection registers the grpc.health.v1 Health service and the
+// server reflection service on s. Every service already registered on s, as
+// well as the overall server (""), is reported as SERVING, so it must be
+// called after the application services are registered.
+func registerIntrospection(s *grpc.Server) *health.Server {
+	healthServer := health.NewServer()
+	for name := range s.GetServiceInfo() {
+		healthServer.SetServingStatus(name, healthpb.HealthCheckResponse_SERVING)
+	}
+	healthServer.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(s, healthServer)
+	reflection.Register(s)
+	return healthServer
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	rpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+)
+
+func dialGRPCServer(t *testing.T) *grpc.ClientConn {
+	startGRPCServer()
+
+	conn, err := grpc.Dial("127.0.0.1:8084", grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn
+}
+
+func TestGRPCHealth(t *testing.T) {
+	conn := dialGRPCServer(t)
+	defer conn.Close()
+	client := healthpb.NewHealthClient(conn)
+
+	for _, service := range []string{"", "agent.Agent"} {
+		res, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+		if err != nil {
+			t.Fatalf("Check(%q): %v", service, err)
+		}
+		if res.Status != healthpb.HealthCheckResponse_SERVING {
+			t.Errorf("Check(%q) = %v, want SERVING", service, res.Status)
+		}
+	}
+
+	grpcHealthServer.SetServingStatus("agent.Agent", healthpb.HealthCheckResponse_NOT_SERVING)
+	defer grpcHealthServer.SetServingStatus("agent.Agent", healthpb.HealthCheckResponse_SERVING)
+	res, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "agent.Agent"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Status != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Errorf("Check(agent.Agent) = %v, want NOT_SERVING", res.Status)
+	}
+
+	if _, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "unknown.Service"}); err == nil {
+		t.Error("Check(unknown.Service) succeeded, want NotFound")
+	}
+}
+
+func TestGRPCReflection(t *testing.T) {
+	conn := dialGRPCServer(t)
+	defer conn.Close()
+
+	stream, err := rpb.NewServerReflectionClient(conn).ServerReflectionInfo(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.CloseSend()
+
+	err = stream.Send(&rpb.ServerReflectionRequest{
+		MessageRequest: &rpb.ServerReflectionRequest_ListServices{ListServices: "*"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var services []string
+	for _, service := range res.GetListServicesResponse().GetService() {
+		services = append(services, service.Name)
+	}
+	sort.Strings(services)
+	want := []string{"agent.Agent", "agent.Arith", "grpc.health.v1.Health", "grpc.reflection.v1alpha.ServerReflection"}
+	if len(services) != len(want) {
+		t.Fatalf("ListServices = %v, want %v", services, want)
+	}
+	for i := range want {
+		if services[i] != want[i] {
+			t.Fatalf("ListServices = %v, want %v", services, want)
+		}
+	}
+
+	err = stream.Send(&rpb.ServerReflectionRequest{
+		MessageRequest: &rpb.ServerReflectionRequest_FileContainingSymbol{FileContainingSymbol: "agent.Agent"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err = stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.GetFileDescriptorResponse().GetFileDescriptorProto()) == 0 {
+		t.Fatalf("FileContainingSymbol(agent.Agent) returned no descriptors: %v", res)
+	}
+}
@@ -0,0 +1,335 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+
+	pb "github.com/evaluate_serde_protocol/protocol/agent"
+	"google.golang.org/grpc"
+)
+
+var idleConns = flag.String("idle.conns", "1000,10000", "comma-separated numbers of idle connections for BenchmarkIdleConnections")
+
+// idleServerEnv names the transport a re-executed test binary should serve
+// in TestIdleServerProcess.
+const idleServerEnv = "IDLE_SERVER_TRANSPORT"
+
+// idleTransport serves one transport and opens client connections to it that
+// have completed one call and then sit idle.
+type idleTransport struct {
+	name    string
+	serve   func(listener net.Listener)
+	connect func(address string) (io.Closer, error)
+}
+
+func connectRPC(client *rpc.Client, err error) (io.Closer, error) {
+	if err != nil {
+		return nil, err
+	}
+	var reply AgentData
+	if err := client.Call("AgentHandler.Serve", "0", &reply); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return client, nil
+}
+
+var idleTransports = []idleTransport{
+	{"TCPRPC", func(listener net.Listener) {
+		acceptLoop(listener, newAgentRPCServer().ServeConn)
+	}, func(address string) (io.Closer, error) {
+		return connectRPC(rpc.Dial("tcp", address))
+	}},
+	{"JSONRPC", func(listener net.Listener) {
+		server := newAgentRPCServer()
+		acceptLoop(listener, func(conn io.ReadWriteCloser) {
+			server.ServeCodec(jsonrpc.NewServerCodec(conn))
+		})
+	}, func(address string) (io.Closer, error) {
+		return connectRPC(jsonrpc.Dial("tcp", address))
+	}},
+	{"HTTPRPC", func(listener net.Listener) {
+		http.Serve(listener, newAgentRPCServer())
+	}, func(address string) (io.Closer, error) {
+		return connectRPC(rpc.DialHTTP("tcp", address))
+	}},
+	{"JSONRPC2", func(listener net.Listener) {
+		server := NewJSONRPCServer()
+		if err := server.Register(new(AgentHandler)); err != nil {
+			panic(err)
+		}
+		server.Accept(listener)
+	}, func(address string) (io.Closer, error) {
+		client, err := DialJSONRPC("tcp", address)
+		if err != nil {
+			return nil, err
+		}
+		var reply AgentData
+		if err := client.Call("AgentHandler.Serve", "0", &reply); err != nil {
+			client.Close()
+			return nil, err
+		}
+		return client, nil
+	}},
+	{"GRPC", func(listener net.Listener) {
+		server := grpc.NewServer()
+		pb.RegisterAgentServer(server, new(AgentHandler))
+		server.Serve(listener)
+	}, func(address string) (io.Closer, error) {
+		conn, err := grpc.Dial(address, grpc.WithInsecure(), grpc.WithBlock())
+		if err != nil {
+			return nil, err
+		}
+		_, err = pb.NewAgentClient(conn).ServeAgentProto(context.Background(), &pb.AgentRequest{Data: "0"})
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return conn, nil
+	}},
+	{"HTTP", func(listener net.Listener) {
+		http.Serve(listener, new(AgentHandler))
+	}, func(address string) (io.Closer, error) {
+		// A raw keep-alive connection is far lighter on the client than
+		// an http.Transport per connection.
+		conn, err := net.Dial("tcp", address)
+		if err != nil {
+			return nil, err
+		}
+		io.WriteString(conn, "GET / HTTP/1.1\r\nHost: "+address+"\r\n\r\n")
+		res, err := http.ReadResponse(bufio.NewReader(conn), nil)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		_, err = io.Copy(ioutil.Discard, res.Body)
+		res.Body.Close()
+		if err != nil || res.StatusCode != http.StatusOK {
+			conn.Close()
+			return nil, fmt.Errorf("GET /: %v %s", err, res.Status)
+		}
+		return conn, nil
+	}},
+}
+
+// serverStats is a snapshot of the resources held by the server process.
+type serverStats struct {
+	heap, stack, goroutines, rss int64
+}
+
+func readServerStats() serverStats {
+	runtime.GC()
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	stats := serverStats{
+		heap:       int64(m.HeapAlloc),
+		stack:      int64(m.StackInuse),
+		goroutines: int64(runtime.NumGoroutine()),
+	}
+	// The second field of statm is the resident set size in pages.
+	if statm, err := ioutil.ReadFile("/proc/self/statm"); err == nil {
+		fields := strings.Fields(string(statm))
+		if len(fields) > 1 {
+			pages, _ := strconv.ParseInt(fields[1], 10, 64)
+			stats.rss = pages * int64(os.Getpagesize())
+		}
+	}
+	return stats
+}
+
+// TestIdleServerProcess is not a real test: BenchmarkIdleConnections
+// re-executes the test binary to run it, so that the server's memory is
+// measured apart from the clients'. It prints the listening address, then
+// answers every line read from stdin with the current serverStats.
+func TestIdleServerProcess(t *testing.T) {
+	name := os.Getenv(idleServerEnv)
+	if name == "" {
+		t.Skip("only run as a child of BenchmarkIdleConnections")
+	}
+	var transport *idleTransport
+	for i := range idleTransports {
+		if idleTransports[i].name == name {
+			transport = &idleTransports[i]
+		}
+	}
+	if transport == nil {
+		t.Fatalf("unknown transport %q", name)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go transport.serve(listener)
+
+	fmt.Println(listener.Addr())
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		stats := readServerStats()
+		fmt.Println(stats.heap, stats.stack, stats.goroutines, stats.rss)
+	}
+	os.Exit(0)
+}
+
+// idleServer is a handle on a server process started for one transport.
+type idleServer struct {
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	stdout  *bufio.Reader
+	address string
+}
+
+func startIdleServer(name string) (*idleServer, error) {
+	cmd := exec.Command(os.Args[0], "-test.run=^TestIdleServerProcess$")
+	cmd.Env = append(os.Environ(), idleServerEnv+"="+name)
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	s := &idleServer{cmd: cmd, stdin: stdin, stdout: bufio.NewReader(stdout)}
+	s.address, err = s.readLine()
+	if err != nil {
+		s.stop()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *idleServer) readLine() (string, error) {
+	line, err := s.stdout.ReadString('\n')
+	return strings.TrimSpace(line), err
+}
+
+func (s *idleServer) stats() (serverStats, error) {
+	var stats serverStats
+	if _, err := io.WriteString(s.stdin, "stats\n"); err != nil {
+		return stats, err
+	}
+	line, err := s.readLine()
+	if err != nil {
+		return stats, err
+	}
+	_, err = fmt.Sscan(line, &stats.heap, &stats.stack, &stats.goroutines, &stats.rss)
+	return stats, err
+}
+
+func (s *idleServer) stop() {
+	s.stdin.Close()
+	s.cmd.Wait()
+}
+
+func TestIdleServer(t *testing.T) {
+	for _, transport := range idleTransports {
+		server, err := startIdleServer(transport.name)
+		if err != nil {
+			t.Fatalf("%s: %v", transport.name, err)
+		}
+		before, err := server.stats()
+		if err != nil {
+			t.Fatalf("%s: %v", transport.name, err)
+		}
+		conn, err := transport.connect(server.address)
+		if err != nil {
+			t.Fatalf("%s: %v", transport.name, err)
+		}
+		after, err := server.stats()
+		if err != nil {
+			t.Fatalf("%s: %v", transport.name, err)
+		}
+		if after.goroutines <= before.goroutines {
+			t.Errorf("%s: %d goroutines with a connection open, %d without", transport.name, after.goroutines, before.goroutines)
+		}
+		conn.Close()
+		server.stop()
+	}
+}
+
+// BenchmarkIdleConnections opens -idle.conns connections to a server running
+// in a separate process, makes one call on each and leaves them idle. It
+// reports the server's heap, goroutine stack, goroutine count and resident
+// set growth per connection; ns/op is the time to open all of them.
+func BenchmarkIdleConnections(b *testing.B) {
+	var counts []int
+	for _, s := range strings.Split(*idleConns, ",") {
+		count, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil || count < 1 {
+			panic("invalid -idle.conns value " + strconv.Quote(s))
+		}
+		counts = append(counts, count)
+	}
+
+	for _, transport := range idleTransports {
+		for _, count := range counts {
+			b.Run(transport.name+"/conns="+strconv.Itoa(count), func(b *testing.B) {
+				server, err := startIdleServer(transport.name)
+				if err != nil {
+					panic(err)
+				}
+				defer server.stop()
+
+				var total serverStats
+				conns := make([]io.Closer, 0, count)
+				for n := 0; n < b.N; n++ {
+					b.StopTimer()
+					before, err := server.stats()
+					if err != nil {
+						panic(err)
+					}
+					b.StartTimer()
+
+					for i := 0; i < count; i++ {
+						conn, err := transport.connect(server.address)
+						if err != nil {
+							panic(err)
+						}
+						conns = append(conns, conn)
+					}
+
+					b.StopTimer()
+					after, err := server.stats()
+					if err != nil {
+						panic(err)
+					}
+					total.heap += after.heap - before.heap
+					total.stack += after.stack - before.stack
+					total.goroutines += after.goroutines - before.goroutines
+					total.rss += after.rss - before.rss
+					for _, conn := range conns {
+						conn.Close()
+					}
+					conns = conns[:0]
+					b.StartTimer()
+				}
+				b.StopTimer()
+
+				perConn := float64(b.N) * float64(count)
+				b.ReportMetric(float64(total.heap)/perConn, "heap-B/conn")
+				b.ReportMetric(float64(total.stack)/perConn, "stack-B/conn")
+				b.ReportMetric(float64(total.goroutines)/perConn, "goroutines/conn")
+				b.ReportMetric(float64(total.rss)/perConn, "rss-B/conn")
+			})
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+)
+
+// Impairment describes the link conditions an ImpairmentProxy simulates. The
+// zero value forwards traffic unchanged.
+type Impairment struct {
+	// Latency is added to every chunk of data in each direction.
+	Latency time.Duration
+	// Jitter adds a uniformly distributed extra delay in [0, Jitter).
+	// Chunks are never reordered.
+	Jitter time.Duration
+	// Bandwidth caps each direction of each connection, in bytes per
+	// second. Zero means unlimited.
+	Bandwidth int64
+	// ResetProbability is the chance that a connection is reset before
+	// forwarding each chunk, simulating a lossy link that drops
+	// connections.
+	ResetProbability float64
+}
+
+// ImpairmentProxy is an in-process TCP proxy that forwards connections to a
+// target address while applying an Impairment, so that transports can be
+// compared under WAN-like conditions without tc/netem or root.
+type ImpairmentProxy struct {
+	listener net.Listener
+	target   string
+
+	mu         sync.Mutex
+	impairment Impairment
+	rand       *rand.Rand
+	conns      map[net.Conn]struct{}
+	closed     bool
+}
+
+// NewImpairmentProxy listens on a loopback port and forwards every accepted
+// connection to target.
+func NewImpairmentProxy(target string, impairment Impairment) (*ImpairmentProxy, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	p := &ImpairmentProxy{
+		listener:   listener,
+		target:     target,
+		impairment: impairment,
+		rand:       rand.New(rand.NewSource(time.Now().UnixNano())),
+		conns:      make(map[net.Conn]struct{}),
+	}
+	go p.serve()
+	return p, nil
+}
+
+// Addr returns the address clients should dial instead of the target.
+func (p *ImpairmentProxy) Addr() string {
+	return p.listener.Addr().String()
+}
+
+// Dial connects to the proxy after waiting one simulated round trip, since
+// the TCP handshake with the local proxy itself does not cross the impaired
+// link. It matches the signature of net.Dial; address is ignored.
+func (p *ImpairmentProxy) Dial(network, address string) (net.Conn, error) {
+	waitUntil(time.Now().Add(p.delay() + p.delay()))
+	return net.Dial(network, p.Addr())
+}
+
+// SetImpairment changes the conditions applied from now on, including to
+// connections that are already open.
+func (p *ImpairmentProxy) SetImpairment(impairment Impairment) {
+	p.mu.Lock()
+	p.impairment = impairment
+	p.mu.Unlock()
+}
+
+// Close stops accepting connections and closes all open ones.
+func (p *ImpairmentProxy) Close() error {
+	p.mu.Lock()
+	p.closed = true
+	for conn := range p.conns {
+		conn.Close()
+	}
+	p.mu.Unlock()
+	return p.listener.Close()
+}
+
+func (p *ImpairmentProxy) serve() {
+	for {
+		client, err := p.listener.Accept()
+		if err != nil {
+			return
+		}
+		server, err := net.Dial("tcp", p.target)
+		if err != nil {
+			client.Close()
+			continue
+		}
+		if !p.track(client, server) {
+			client.Close()
+			server.Close()
+			return
+		}
+		go func() {
+			var wg sync.WaitGroup
+			wg.Add(2)
+			go func() {
+				p.pipe(server, client)
+				wg.Done()
+			}()
+			go func() {
+				p.pipe(client, server)
+				wg.Done()
+			}()
+			wg.Wait()
+			client.Close()
+			server.Close()
+			p.untrack(client, server)
+		}()
+	}
+}
+
+func (p *ImpairmentProxy) track(conns ...net.Conn) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return false
+	}
+	for _, conn := range conns {
+		p.conns[conn] = struct{}{}
+	}
+	return true
+}
+
+func (p *ImpairmentProxy) untrack(conns ...net.Conn) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, conn := range conns {
+		delete(p.conns, conn)
+	}
+}
+
+// chunk is a piece of data read from one side, due for delivery at a given
+// time.
+type chunk struct {
+	data []byte
+	due  time.Time
+}
+
+// pipe copies src to dst. A reader goroutine timestamps chunks as they
+// arrive so that latency overlaps with transmission the way it does on a
+// real link; the writer delays each chunk until it is due and paces writes
+// to the bandwidth cap. EOF is forwarded as a half-close; any other failure,
+// or a simulated reset, aborts both connections.
+func (p *ImpairmentProxy) pipe(dst, src net.Conn) {
+	chunks := make(chan chunk, 64)
+	go func() {
+		defer close(chunks)
+		var last time.Time
+		for {
+			buf := make([]byte, 32*1024)
+			n, err := src.Read(buf)
+			if n > 0 {
+				due := time.Now().Add(p.delay())
+				// Never deliver a chunk before the previous one.
+				if due.Before(last) {
+					due = last
+				}
+				last = due
+				chunks <- chunk{data: buf[:n], due: due}
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	// Unblock the reader if the writer gives up early; it exits once src
+	// is closed.
+	defer func() {
+		for range chunks {
+		}
+	}()
+
+	var sendFree time.Time
+	for c := range chunks {
+		impairment := p.current()
+		if impairment.ResetProbability > 0 && p.randFloat() < impairment.ResetProbability {
+			reset(dst, src)
+			return
+		}
+
+		waitUntil(c.due)
+		if impairment.Bandwidth > 0 {
+			// Serialization delay: the link is busy for len/bandwidth.
+			now := time.Now()
+			if sendFree.Before(now) {
+				sendFree = now
+			}
+			sendFree = sendFree.Add(time.Duration(int64(len(c.data)) * int64(time.Second) / impairment.Bandwidth))
+			waitUntil(sendFree)
+		}
+		if _, err := dst.Write(c.data); err != nil {
+			dst.Close()
+			src.Close()
+			return
+		}
+	}
+	if tcp, ok := dst.(*net.TCPConn); ok {
+		tcp.CloseWrite()
+	}
+}
+
+// waitUntil blocks until t. It sleeps rather than spins: the proxy shares
+// CPUs with the client and server it sits between, and spinning would slow
+// down the very calls being measured. Sleeps can overshoot by the timer
+// granularity, so very small latencies come out somewhat larger than
+// configured.
+func waitUntil(t time.Time) {
+	if d := time.Until(t); d > 0 {
+		time.Sleep(d)
+	}
+}
+
+// reset aborts conns with a TCP RST instead of an orderly shutdown.
+func reset(conns ...net.Conn) {
+	for _, conn := range conns {
+		if tcp, ok := conn.(*net.TCPConn); ok {
+			tcp.SetLinger(0)
+		}
+		conn.Close()
+	}
+}
+
+func (p *ImpairmentProxy) current() Impairment {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.impairment
+}
+
+func (p *ImpairmentProxy) delay() time.Duration {
+	impairment := p.current()
+	d := impairment.Latency
+	if impairment.Jitter > 0 {
+		d += time.Duration(p.randFloat() * float64(impairment.Jitter))
+	}
+	return d
+}
+
+func (p *ImpairmentProxy) randFloat() float64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.rand.Float64()
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+var (
+	impairLatency   = flag.Duration("impair.latency", 0, "one-way latency added by the impairment proxy in BenchmarkImpaired")
+	impairJitter    = flag.Duration("impair.jitter", 0, "maximum extra random one-way delay added by the impairment proxy")
+	impairBandwidth = flag.Int64("impair.bandwidth", 0, "per-direction bandwidth cap of the impairment proxy in bytes/s, 0 for unlimited")
+	impairReset     = flag.Float64("impair.reset", 0, "probability that the impairment proxy resets a connection before forwarding each chunk")
+)
+
+type impairmentProfile struct {
+	name       string
+	impairment Impairment
+}
+
+// impairmentProfiles are the link conditions BenchmarkImpaired runs under
+// unless a custom one is given with the -impair.* flags.
+var impairmentProfiles = []impairmentProfile{
+	{"loopback", Impairment{}},
+	{"lan", Impairment{Latency: 250 * time.Microsecond, Jitter: 100 * time.Microsecond, Bandwidth: 100 << 20}},
+	{"wan", Impairment{Latency: 10 * time.Millisecond, Jitter: 2 * time.Millisecond, Bandwidth: 1 << 20}},
+}
+
+// startEchoServer echoes every connection back to itself.
+func startEchoServer(t *testing.T) net.Listener {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go acceptLoop(listener, func(conn io.ReadWriteCloser) {
+		io.Copy(conn, conn)
+		conn.Close()
+	})
+	return listener
+}
+
+// echoThroughProxy sends payload through a proxy applying impairment and
+// returns how long the echo took.
+func echoThroughProxy(t *testing.T, impairment Impairment, payload []byte) (time.Duration, error) {
+	listener := startEchoServer(t)
+	defer listener.Close()
+	proxy, err := NewImpairmentProxy(listener.Addr().String(), impairment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.Close()
+
+	conn, err := net.Dial("tcp", proxy.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	start := time.Now()
+	go conn.Write(payload)
+	echo := make([]byte, len(payload))
+	if _, err := io.ReadFull(conn, echo); err != nil {
+		return 0, err
+	}
+	if !bytes.Equal(echo, payload) {
+		t.Fatal("echo does not match payload")
+	}
+	return time.Since(start), nil
+}
+
+func TestImpairmentProxyLatency(t *testing.T) {
+	elapsed, err := echoThroughProxy(t, Impairment{Latency: 20 * time.Millisecond, Jitter: 5 * time.Millisecond}, []byte("ping"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed < 40*time.Millisecond {
+		t.Errorf("round trip took %s, want at least 40ms", elapsed)
+	}
+}
+
+func TestImpairmentProxyBandwidth(t *testing.T) {
+	payload := bytes.Repeat([]byte("x"), 64<<10)
+	elapsed, err := echoThroughProxy(t, Impairment{Bandwidth: 1 << 20}, payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 64KiB at 1MiB/s takes 62.5ms in each direction, which overlap as
+	// the echo streams back.
+	if elapsed < 60*time.Millisecond {
+		t.Errorf("transfer took %s, want at least 60ms", elapsed)
+	}
+}
+
+func TestImpairmentProxyReset(t *testing.T) {
+	_, err := echoThroughProxy(t, Impairment{ResetProbability: 1}, []byte("ping"))
+	if err == nil {
+		t.Fatal("echo through a resetting proxy succeeded")
+	}
+}
+
+// BenchmarkImpaired runs the transports of BenchmarkWireBytes through an
+// ImpairmentProxy. Pass -impair.latency, -impair.jitter, -impair.bandwidth
+// and -impair.reset to run under custom conditions instead of the built-in
+// profiles. Calls that fail because their connection was reset are counted
+// in failed/op, and the transport is restarted with the timer stopped.
+func BenchmarkImpaired(b *testing.B) {
+	profiles := impairmentProfiles
+	if *impairLatency > 0 || *impairJitter > 0 || *impairBandwidth > 0 || *impairReset > 0 {
+		profiles = []impairmentProfile{
+			{"custom", Impairment{Latency: *impairLatency, Jitter: *impairJitter, Bandwidth: *impairBandwidth, ResetProbability: *impairReset}},
+		}
+	}
+
+	for _, profile := range profiles {
+		for _, transport := range wireTransports {
+			b.Run(profile.name+"/"+transport.name, func(b *testing.B) {
+				call, stop := startImpaired(transport, profile.impairment)
+				defer func() { stop() }()
+
+				failed := 0
+				b.ResetTimer()
+				for n := 0; n < b.N; n++ {
+					if profile.impairment.ResetProbability == 0 {
+						call(n)
+						continue
+					}
+					if !tryCall(call, n) {
+						failed++
+						b.StopTimer()
+						stop()
+						call, stop = startImpaired(transport, profile.impairment)
+						b.StartTimer()
+					}
+				}
+				if profile.impairment.ResetProbability > 0 {
+					b.ReportMetric(float64(failed)/float64(b.N), "failed/op")
+				}
+			})
+		}
+	}
+}
+
+// startImpaired starts transport behind a new ImpairmentProxy applying
+// impairment and makes one warm-up call, during which no connection is
+// reset.
+func startImpaired(transport wireTransport, impairment Impairment) (func(int), func()) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(err)
+	}
+	warmup := impairment
+	warmup.ResetProbability = 0
+	proxy, err := NewImpairmentProxy(listener.Addr().String(), warmup)
+	if err != nil {
+		panic(err)
+	}
+	call, stop := transport.start(listener, proxy.Dial)
+	call(0)
+	proxy.SetImpairment(impairment)
+	return call, func() {
+		stop()
+		proxy.Close()
+	}
+}
+
+// tryCall reports whether call(n) succeeded. The callers of wireTransports
+// panic on errors, such as a connection reset by the proxy.
+func tryCall(call func(int), n int) (ok bool) {
+	defer func() {
+		if recover() != nil {
+			ok = false
+		}
+	}()
+	call(n)
+	return true
+}
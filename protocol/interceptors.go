@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const authorizationHeader = "authorization"
+
+// CallMetrics accumulates call counts and latency for the metrics
+// interceptors. It is safe for concurrent use.
+type CallMetrics struct {
+	calls   int64
+	errors  int64
+	latency int64
+}
+
+// Observe records a single call that took d and ended with err.
+func (m *CallMetrics) Observe(d time.Duration, err error) {
+	atomic.AddInt64(&m.calls, 1)
+	atomic.AddInt64(&m.latency, int64(d))
+	if err != nil {
+		atomic.AddInt64(&m.errors, 1)
+	}
+}
+
+// Calls returns the number of observed calls.
+func (m *CallMetrics) Calls() int64 {
+	return atomic.LoadInt64(&m.calls)
+}
+
+// Errors returns the number of observed calls that failed.
+func (m *CallMetrics) Errors() int64 {
+	return atomic.LoadInt64(&m.errors)
+}
+
+// MeanLatency returns the average latency of the observed calls.
+func (m *CallMetrics) MeanLatency() time.Duration {
+	calls := m.Calls()
+	if calls == 0 {
+		return 0
+	}
+	return time.Duration(atomic.LoadInt64(&m.latency) / calls)
+}
+
+// LoggingUnaryServerInterceptor logs the method, latency and status code
+// of every unary call.
+func LoggingUnaryServerInterceptor(logger *log.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		logger.Printf("%s %s %s", info.FullMethod, status.Code(err), time.Since(start))
+		return resp, err
+	}
+}
+
+// LoggingStreamServerInterceptor logs the method, duration and status code
+// of every stream.
+func LoggingStreamServerInterceptor(logger *log.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		logger.Printf("%s %s %s", info.FullMethod, status.Code(err), time.Since(start))
+		return err
+	}
+}
+
+// MetricsUnaryServerInterceptor records every unary call in m.
+func MetricsUnaryServerInterceptor(m *CallMetrics) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		m.Observe(time.Since(start), err)
+		return resp, err
+	}
+}
+
+// MetricsStreamServerInterceptor records every stream in m.
+func MetricsStreamServerInterceptor(m *CallMetrics) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		m.Observe(time.Since(start), err)
+		return err
+	}
+}
+
+// AuthUnaryServerInterceptor rejects unary calls that do not carry
+// "authorization: Bearer <token>" metadata.
+func AuthUnaryServerInterceptor(token string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := checkBearerToken(ctx, token); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// AuthStreamServerInterceptor rejects streams that do not carry
+// "authorization: Bearer <token>" metadata.
+func AuthStreamServerInterceptor(token string) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := checkBearerToken(ss.Context(), token); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+func checkBearerToken(ctx context.Context, token string) error {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return status.Error(codes.Unauthenticated, "missing metadata")
+	}
+	for _, v := range md.Get(authorizationHeader) {
+		if v == "Bearer "+token {
+			return nil
+		}
+	}
+	return status.Error(codes.Unauthenticated, "invalid bearer token")
+}
+
+// RecoveryUnaryServerInterceptor turns a panicking handler into a
+// codes.Internal error instead of crashing the server, and logs the panic
+// and its stack to logger.
+func RecoveryUnaryServerInterceptor(logger *log.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = recoveredError(logger, info.FullMethod, r)
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
+
+// RecoveryStreamServerInterceptor turns a panicking stream handler into a
+// codes.Internal error instead of crashing the server, and logs the panic
+// and its stack to logger.
+func RecoveryStreamServerInterceptor(logger *log.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = recoveredError(logger, info.FullMethod, r)
+			}
+		}()
+		return handler(srv, ss)
+	}
+}
+
+func recoveredError(logger *log.Logger, method string, r interface{}) error {
+	logger.Printf("panic in %s: %v\n%s", method, r, debug.Stack())
+	return status.Error(codes.Internal, fmt.Sprintf("panic: %v", r))
+}
+
+// LoggingUnaryClientInterceptor logs the method, latency and status code
+// of every outgoing unary call.
+func LoggingUnaryClientInterceptor(logger *log.Logger) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		start := time.Now()
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		logger.Printf("%s %s %s", method, status.Code(err), time.Since(start))
+		return err
+	}
+}
+
+// MetricsUnaryClientInterceptor records every outgoing unary call in m.
+func MetricsUnaryClientInterceptor(m *CallMetrics) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		start := time.Now()
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		m.Observe(time.Since(start), err)
+		return err
+	}
+}
+
+// AuthUnaryClientInterceptor attaches "authorization: Bearer <token>"
+// metadata to every outgoing unary call.
+func AuthUnaryClientInterceptor(token string) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx = metadata.AppendToOutgoingContext(ctx, authorizationHeader, "Bearer "+token)
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// AuthStreamClientInterceptor attaches "authorization: Bearer <token>"
+// metadata to every outgoing stream.
+func AuthStreamClientInterceptor(token string) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		ctx = metadata.AppendToOutgoingContext(ctx, authorizationHeader, "Bearer "+token)
+		return streamer(ctx, desc, cc, method, opts...)
+	}
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+
+	pb "github.com/evaluate_serde_protocol/protocol/agent"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const benchmarkToken = "benchmark-token"
+
+type panicAgent struct {
+	pb.UnimplementedAgentServer
+}
+
+func (panicAgent) ServeAgentProto(ctx context.Context, in *pb.AgentRequest) (*pb.AgentProto, error) {
+	panic("boom")
+}
+
+// startAgentGRPCServer serves srv on an ephemeral loopback port and returns
+// the address and a function that stops the server.
+func startAgentGRPCServer(srv pb.AgentServer, opts ...grpc.ServerOption) (string, func()) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(err)
+	}
+	grpcServer := grpc.NewServer(opts...)
+	pb.RegisterAgentServer(grpcServer, srv)
+	go grpcServer.Serve(listener)
+	return listener.Addr().String(), grpcServer.Stop
+}
+
+// serverInterceptors returns the first n interceptors of the production
+// stack, outermost first.
+func serverInterceptors(n int, metrics *CallMetrics) []grpc.UnaryServerInterceptor {
+	logger := log.New(ioutil.Discard, "", log.LstdFlags)
+	all := []grpc.UnaryServerInterceptor{
+		RecoveryUnaryServerInterceptor(logger),
+		AuthUnaryServerInterceptor(benchmarkToken),
+		MetricsUnaryServerInterceptor(metrics),
+		LoggingUnaryServerInterceptor(logger),
+	}
+	return all[:n]
+}
+
+func TestAuthInterceptor(t *testing.T) {
+	addr, stop := startAgentGRPCServer(new(AgentHandler), grpc.UnaryInterceptor(AuthUnaryServerInterceptor(benchmarkToken)))
+	defer stop()
+
+	conn, err := grpc.Dial(addr, grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	_, err = pb.NewAgentClient(conn).ServeAgentProto(context.Background(), &pb.AgentRequest{})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("call without token: got %v, want %v", err, codes.Unauthenticated)
+	}
+
+	authConn, err := grpc.Dial(addr, grpc.WithInsecure(), grpc.WithUnaryInterceptor(AuthUnaryClientInterceptor(benchmarkToken)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer authConn.Close()
+	reply, err := pb.NewAgentClient(authConn).ServeAgentProto(context.Background(), &pb.AgentRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply.Hostname != generateObject().Hostname {
+		t.Fatalf("unexpected reply %v", reply)
+	}
+}
+
+func TestRecoveryInterceptor(t *testing.T) {
+	var logs bytes.Buffer
+	metrics := new(CallMetrics)
+	addr, stop := startAgentGRPCServer(&panicAgent{}, grpc.ChainUnaryInterceptor(
+		MetricsUnaryServerInterceptor(metrics),
+		RecoveryUnaryServerInterceptor(log.New(&logs, "", 0)),
+	))
+	defer stop()
+
+	conn, err := grpc.Dial(addr, grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	_, err = pb.NewAgentClient(conn).ServeAgentProto(context.Background(), &pb.AgentRequest{})
+	if status.Code(err) != codes.Internal {
+		t.Fatalf("got %v, want %v", err, codes.Internal)
+	}
+	if metrics.Calls() != 1 || metrics.Errors() != 1 {
+		t.Fatalf("metrics recorded %d calls, %d errors; want 1, 1", metrics.Calls(), metrics.Errors())
+	}
+	if !strings.HasPrefix(logs.String(), "panic in /agent.Agent/ServeAgentProto: boom\n") {
+		t.Errorf("logged %q", logs.String())
+	}
+}
+
+// panicStreamService is a server-streaming service whose only method
+// panics, since the Agent service has no streams of its own.
+var panicStreamService = grpc.ServiceDesc{
+	ServiceName: "test.Panic",
+	HandlerType: (*interface{})(nil),
+	Streams: []grpc.StreamDesc{{
+		StreamName: "Boom",
+		Handler: func(srv interface{}, stream grpc.ServerStream) error {
+			panic("boom")
+		},
+		ServerStreams: true,
+	}},
+}
+
+func TestStreamInterceptors(t *testing.T) {
+	var logs bytes.Buffer
+	logger := log.New(&logs, "", 0)
+	metrics := new(CallMetrics)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	grpcServer := grpc.NewServer(grpc.ChainStreamInterceptor(
+		LoggingStreamServerInterceptor(logger),
+		MetricsStreamServerInterceptor(metrics),
+		RecoveryStreamServerInterceptor(logger),
+		AuthStreamServerInterceptor(benchmarkToken),
+	))
+	grpcServer.RegisterService(&panicStreamService, struct{}{})
+	go grpcServer.Serve(listener)
+	defer grpcServer.Stop()
+
+	boom := func(opts ...grpc.DialOption) error {
+		conn, err := grpc.Dial(listener.Addr().String(), append(opts, grpc.WithInsecure())...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		stream, err := conn.NewStream(context.Background(), &panicStreamService.Streams[0], "/test.Panic/Boom")
+		if err != nil {
+			return err
+		}
+		if err := stream.CloseSend(); err != nil {
+			return err
+		}
+		return stream.RecvMsg(new(pb.AgentProto))
+	}
+
+	if err := boom(); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("stream without token: got %v, want %v", err, codes.Unauthenticated)
+	}
+	if !strings.HasPrefix(logs.String(), "/test.Panic/Boom Unauthenticated ") {
+		t.Errorf("logged %q", logs.String())
+	}
+	logs.Reset()
+
+	err = boom(grpc.WithStreamInterceptor(AuthStreamClientInterceptor(benchmarkToken)))
+	if status.Code(err) != codes.Internal {
+		t.Fatalf("panicking stream: got %v, want %v", err, codes.Internal)
+	}
+	if !strings.HasPrefix(logs.String(), "panic in /test.Panic/Boom: boom\n") || !strings.Contains(logs.String(), "\n/test.Panic/Boom Internal ") {
+		t.Errorf("logged %q", logs.String())
+	}
+	if metrics.Calls() != 2 || metrics.Errors() != 2 {
+		t.Errorf("metrics recorded %d calls, %d errors; want 2, 2", metrics.Calls(), metrics.Errors())
+	}
+}
+
+func BenchmarkGRPCInterceptors(b *testing.B) {
+	for n := 0; n <= 4; n++ {
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			metrics := new(CallMetrics)
+			addr, stop := startAgentGRPCServer(new(AgentHandler), grpc.ChainUnaryInterceptor(serverInterceptors(n, metrics)...))
+			defer stop()
+
+			conn, err := grpc.Dial(addr, grpc.WithInsecure(), grpc.WithUnaryInterceptor(AuthUnaryClientInterceptor(benchmarkToken)))
+			if err != nil {
+				panic(err)
+			}
+			defer conn.Close()
+			client := pb.NewAgentClient(conn)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_, err := client.ServeAgentProto(context.Background(), &pb.AgentRequest{Data: strconv.Itoa(i)})
+				if err != nil {
+					panic(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkGRPCClientInterceptors(b *testing.B) {
+	addr, stop := startAgentGRPCServer(new(AgentHandler))
+	defer stop()
+
+	metrics := new(CallMetrics)
+	logger := log.New(ioutil.Discard, "", log.LstdFlags)
+	conn, err := grpc.Dial(addr, grpc.WithInsecure(), grpc.WithChainUnaryInterceptor(
+		LoggingUnaryClientInterceptor(logger),
+		MetricsUnaryClientInterceptor(metrics),
+		AuthUnaryClientInterceptor(benchmarkToken),
+	))
+	if err != nil {
+		panic(err)
+	}
+	defer conn.Close()
+	client := pb.NewAgentClient(conn)
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		_, err := client.ServeAgentProto(context.Background(), &pb.AgentRequest{Data: strconv.Itoa(n)})
+		if err != nil {
+			panic(err)
+		}
+	}
+}
@@ -0,0 +1,577 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// This file implements JSON-RPC 2.0 (https://www.jsonrpc.org/specification)
+// for receivers written against net/rpc conventions, i.e. exported methods of
+// the form
+//
+//	func (t *T) MethodName(args T1, reply *T2) error
+//
+// which are called as "T.MethodName". Positional params must hold exactly one
+// element, the args value; named params are decoded into args directly.
+
+const jsonrpcVersion = "2.0"
+
+// Standard JSON-RPC 2.0 error codes.
+const (
+	JSONRPCParseError     = -32700
+	JSONRPCInvalidRequest = -32600
+	JSONRPCMethodNotFound = -32601
+	JSONRPCInvalidParams  = -32602
+	JSONRPCInternalError  = -32603
+	// JSONRPCServerError is used for errors returned by the called method.
+	JSONRPCServerError = -32000
+)
+
+// JSONRPCError is a JSON-RPC 2.0 error object. Methods may return a
+// *JSONRPCError to control the code and data sent to the client.
+type JSONRPCError struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+func (e *JSONRPCError) Error() string {
+	return fmt.Sprintf("jsonrpc2: code %d: %s", e.Code, e.Message)
+}
+
+type jsonrpcRequest struct {
+	Version string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	// ID is empty for notifications. An explicit "id": null decodes as
+	// the literal null, since RawMessage receives null values verbatim, and
+	// still gets a response.
+	ID json.RawMessage `json:"id,omitempty"`
+}
+
+type jsonrpcResponse struct {
+	Version string          `json:"jsonrpc"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *JSONRPCError   `json:"error,omitempty"`
+	ID      json.RawMessage `json:"id"`
+}
+
+var (
+	typeOfError = reflect.TypeOf((*error)(nil)).Elem()
+	nullID      = json.RawMessage("null")
+)
+
+type jsonrpcMethod struct {
+	rcvr      reflect.Value
+	method    reflect.Method
+	argType   reflect.Type
+	replyType reflect.Type
+}
+
+// JSONRPCServer dispatches JSON-RPC 2.0 requests, including notifications
+// and batches, over stream connections and HTTP.
+type JSONRPCServer struct {
+	mu      sync.RWMutex
+	methods map[string]*jsonrpcMethod
+}
+
+// NewJSONRPCServer returns a server with no registered receivers.
+func NewJSONRPCServer() *JSONRPCServer {
+	return &JSONRPCServer{methods: make(map[string]*jsonrpcMethod)}
+}
+
+// Register publishes the suitable methods of rcvr under its type name.
+func (s *JSONRPCServer) Register(rcvr interface{}) error {
+	return s.RegisterName(reflect.Indirect(reflect.ValueOf(rcvr)).Type().Name(), rcvr)
+}
+
+// RegisterName publishes the suitable methods of rcvr under name.
+func (s *JSONRPCServer) RegisterName(name string, rcvr interface{}) error {
+	typ := reflect.TypeOf(rcvr)
+	methods := make(map[string]*jsonrpcMethod)
+	for i := 0; i < typ.NumMethod(); i++ {
+		method := typ.Method(i)
+		mtype := method.Type
+		if method.PkgPath != "" || mtype.NumIn() != 3 || mtype.NumOut() != 1 {
+			continue
+		}
+		argType, replyType := mtype.In(1), mtype.In(2)
+		if replyType.Kind() != reflect.Ptr || !isExportedOrBuiltin(argType) || !isExportedOrBuiltin(replyType) || mtype.Out(0) != typeOfError {
+			continue
+		}
+		methods[name+"."+method.Name] = &jsonrpcMethod{
+			rcvr:      reflect.ValueOf(rcvr),
+			method:    method,
+			argType:   argType,
+			replyType: replyType.Elem(),
+		}
+	}
+	if len(methods) == 0 {
+		return fmt.Errorf("jsonrpc2: type %s has no suitable methods", typ)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k, v := range methods {
+		s.methods[k] = v
+	}
+	return nil
+}
+
+func isExportedOrBuiltin(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.PkgPath() == "" {
+		return true
+	}
+	r, _ := utf8.DecodeRuneInString(t.Name())
+	return unicode.IsUpper(r)
+}
+
+// ServeConn serves newline-delimited or concatenated JSON-RPC messages on conn
+// until the client hangs up.
+func (s *JSONRPCServer) ServeConn(conn io.ReadWriteCloser) {
+	defer conn.Close()
+	dec := json.NewDecoder(bufio.NewReader(conn))
+	enc := json.NewEncoder(conn)
+	for {
+		var msg json.RawMessage
+		if err := dec.Decode(&msg); err != nil {
+			if err != io.EOF {
+				enc.Encode(errorResponse(nullID, &JSONRPCError{Code: JSONRPCParseError, Message: err.Error()}))
+			}
+			return
+		}
+		if reply := s.handleMessage(msg); reply != nil {
+			if err := enc.Encode(reply); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// Accept serves every connection accepted from listener.
+func (s *JSONRPCServer) Accept(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.ServeConn(conn)
+	}
+}
+
+// ServeHTTP handles one JSON-RPC message per POST. Requests consisting only
+// of notifications are answered with 204 No Content.
+func (s *JSONRPCServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	reply := s.handleMessage(body)
+	if reply == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	out, err := json.Marshal(reply)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(out)
+}
+
+// handleMessage processes a single request or a batch and returns the value
+// to send back, or nil if nothing must be sent.
+func (s *JSONRPCServer) handleMessage(msg json.RawMessage) interface{} {
+	msg = bytes.TrimSpace(msg)
+	if len(msg) == 0 || msg[0] != '[' {
+		if resp := s.handleRequest(msg); resp != nil {
+			return resp
+		}
+		return nil
+	}
+
+	var batch []json.RawMessage
+	if err := json.Unmarshal(msg, &batch); err != nil {
+		return errorResponse(nullID, &JSONRPCError{Code: JSONRPCParseError, Message: err.Error()})
+	}
+	if len(batch) == 0 {
+		return errorResponse(nullID, &JSONRPCError{Code: JSONRPCInvalidRequest, Message: "empty batch"})
+	}
+	var replies []*jsonrpcResponse
+	for _, req := range batch {
+		if resp := s.handleRequest(req); resp != nil {
+			replies = append(replies, resp)
+		}
+	}
+	if len(replies) == 0 {
+		return nil
+	}
+	return replies
+}
+
+// handleRequest processes a single request and returns nil for
+// notifications.
+func (s *JSONRPCServer) handleRequest(raw json.RawMessage) *jsonrpcResponse {
+	var req jsonrpcRequest
+	if err := json.Unmarshal(raw, &req); err != nil {
+		if _, ok := err.(*json.SyntaxError); ok {
+			return errorResponse(nullID, &JSONRPCError{Code: JSONRPCParseError, Message: err.Error()})
+		}
+		return errorResponse(nullID, &JSONRPCError{Code: JSONRPCInvalidRequest, Message: err.Error()})
+	}
+	id := req.ID
+	if len(id) == 0 {
+		id = nullID
+	}
+	if req.Version != jsonrpcVersion || req.Method == "" {
+		return errorResponse(id, &JSONRPCError{Code: JSONRPCInvalidRequest, Message: "invalid request"})
+	}
+
+	result, rpcErr := s.call(req.Method, req.Params)
+	if len(req.ID) == 0 {
+		return nil
+	}
+	if rpcErr != nil {
+		return errorResponse(id, rpcErr)
+	}
+	out, err := json.Marshal(result)
+	if err != nil {
+		return errorResponse(id, &JSONRPCError{Code: JSONRPCInternalError, Message: err.Error()})
+	}
+	return &jsonrpcResponse{Version: jsonrpcVersion, Result: out, ID: id}
+}
+
+func (s *JSONRPCServer) call(name string, params json.RawMessage) (interface{}, *JSONRPCError) {
+	s.mu.RLock()
+	m, ok := s.methods[name]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, &JSONRPCError{Code: JSONRPCMethodNotFound, Message: "method not found: " + name}
+	}
+
+	argv := reflect.New(m.argType)
+	if m.argType.Kind() == reflect.Ptr {
+		argv = reflect.New(m.argType.Elem())
+	}
+	if err := decodeParams(params, argv.Interface()); err != nil {
+		return nil, &JSONRPCError{Code: JSONRPCInvalidParams, Message: err.Error()}
+	}
+	if m.argType.Kind() != reflect.Ptr {
+		argv = argv.Elem()
+	}
+
+	replyv := reflect.New(m.replyType)
+	out := m.method.Func.Call([]reflect.Value{m.rcvr, argv, replyv})
+	if errInter := out[0].Interface(); errInter != nil {
+		var rpcErr *JSONRPCError
+		if errors.As(errInter.(error), &rpcErr) {
+			return nil, rpcErr
+		}
+		var statusErr *StatusError
+		if errors.As(errInter.(error), &statusErr) {
+			return nil, statusErr.JSONRPCError()
+		}
+		return nil, &JSONRPCError{Code: JSONRPCServerError, Message: errInter.(error).Error()}
+	}
+	return replyv.Interface(), nil
+}
+
+func decodeParams(params json.RawMessage, arg interface{}) error {
+	params = bytes.TrimSpace(params)
+	if len(params) == 0 {
+		return nil
+	}
+	switch params[0] {
+	case '[':
+		var positional []json.RawMessage
+		if err := json.Unmarshal(params, &positional); err != nil {
+			return err
+		}
+		if len(positional) != 1 {
+			return fmt.Errorf("expected 1 positional param, got %d", len(positional))
+		}
+		return json.Unmarshal(positional[0], arg)
+	case '{':
+		return json.Unmarshal(params, arg)
+	}
+	return errors.New("params must be an array or an object")
+}
+
+func errorResponse(id json.RawMessage, err *JSONRPCError) *jsonrpcResponse {
+	return &jsonrpcResponse{Version: jsonrpcVersion, Error: err, ID: id}
+}
+
+// JSONRPCCall is a single call of a batch. Leave Reply nil and set
+// Notification to send a notification.
+type JSONRPCCall struct {
+	Method       string
+	Params       interface{}
+	Reply        interface{}
+	Notification bool
+	// Error is set after the batch completes if the call failed.
+	Error error
+}
+
+// jsonrpcTransport sends one encoded message and returns the reply, or nil
+// if no reply is expected. It stops waiting for the reply once ctx is done.
+type jsonrpcTransport interface {
+	roundTrip(ctx context.Context, msg interface{}, expectReply bool) (json.RawMessage, error)
+	Close() error
+}
+
+// JSONRPCClient is a JSON-RPC 2.0 client. It is safe for concurrent use, but
+// calls on the same client are serialized.
+type JSONRPCClient struct {
+	mu        sync.Mutex
+	seq       uint64
+	transport jsonrpcTransport
+}
+
+// DialJSONRPC connects to a JSON-RPC 2.0 server over a stream connection.
+func DialJSONRPC(network, address string) (*JSONRPCClient, error) {
+	conn, err := net.Dial(network, address)
+	if err != nil {
+		return nil, err
+	}
+	return NewJSONRPCClient(conn), nil
+}
+
+// NewJSONRPCClient returns a client speaking JSON-RPC 2.0 over conn.
+func NewJSONRPCClient(conn io.ReadWriteCloser) *JSONRPCClient {
+	return &JSONRPCClient{transport: &jsonrpcStreamTransport{
+		conn: conn,
+		enc:  json.NewEncoder(conn),
+		dec:  json.NewDecoder(bufio.NewReader(conn)),
+	}}
+}
+
+// NewJSONRPCHTTPClient returns a client posting JSON-RPC 2.0 messages to url.
+func NewJSONRPCHTTPClient(client *http.Client, url string) *JSONRPCClient {
+	return &JSONRPCClient{transport: &jsonrpcHTTPTransport{client: client, url: url}}
+}
+
+// Call invokes method and decodes its result into reply. Struct and map
+// params are sent by name, anything else as a single positional param.
+func (c *JSONRPCClient) Call(method string, params, reply interface{}) error {
+	return c.CallContext(context.Background(), method, params, reply)
+}
+
+// CallContext is like Call but returns a StatusError with code
+// DeadlineExceeded or Canceled as soon as ctx is done. The server is not
+// told that the call was abandoned, so it keeps running it.
+func (c *JSONRPCClient) CallContext(ctx context.Context, method string, params, reply interface{}) error {
+	call := &JSONRPCCall{Method: method, Params: params, Reply: reply}
+	if err := c.BatchContext(ctx, call); err != nil {
+		return err
+	}
+	return call.Error
+}
+
+// Notify sends a notification, which the server never answers.
+func (c *JSONRPCClient) Notify(method string, params interface{}) error {
+	return c.Batch(&JSONRPCCall{Method: method, Params: params, Notification: true})
+}
+
+// Batch sends calls as one message. A single call is sent as a plain request
+// rather than a one-element batch. Per-call failures are reported in each
+// call's Error; the returned error covers transport failures only.
+func (c *JSONRPCClient) Batch(calls ...*JSONRPCCall) error {
+	return c.BatchContext(context.Background(), calls...)
+}
+
+// BatchContext is like Batch but returns a StatusError with code
+// DeadlineExceeded or Canceled as soon as ctx is done. Over HTTP, ctx is
+// the context of the request.
+func (c *JSONRPCClient) BatchContext(ctx context.Context, calls ...*JSONRPCCall) error {
+	if err := ctx.Err(); err != nil {
+		return contextError(err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	reqs := make([]*jsonrpcRequest, len(calls))
+	pending := make(map[string]*JSONRPCCall, len(calls))
+	for i, call := range calls {
+		params, err := encodeParams(call.Params)
+		if err != nil {
+			return err
+		}
+		reqs[i] = &jsonrpcRequest{Version: jsonrpcVersion, Method: call.Method, Params: params}
+		if !call.Notification {
+			c.seq++
+			id := json.RawMessage(fmt.Sprint(c.seq))
+			reqs[i].ID = id
+			pending[string(id)] = call
+		}
+	}
+
+	var msg interface{} = reqs
+	if len(reqs) == 1 {
+		msg = reqs[0]
+	}
+	raw, err := c.transport.roundTrip(ctx, msg, len(pending) > 0)
+	if err != nil && ctx.Err() != nil {
+		return contextError(ctx.Err())
+	}
+	if err != nil || len(pending) == 0 {
+		return err
+	}
+
+	var resps []*jsonrpcResponse
+	raw = bytes.TrimSpace(raw)
+	if len(raw) > 0 && raw[0] == '[' {
+		err = json.Unmarshal(raw, &resps)
+	} else {
+		resp := &jsonrpcResponse{}
+		err = json.Unmarshal(raw, resp)
+		resps = append(resps, resp)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, resp := range resps {
+		call, ok := pending[string(resp.ID)]
+		if !ok {
+			if resp.Error != nil {
+				return resp.Error
+			}
+			return fmt.Errorf("jsonrpc2: unexpected response id %s", resp.ID)
+		}
+		delete(pending, string(resp.ID))
+		if resp.Error != nil {
+			call.Error = resp.Error
+		} else if call.Reply != nil {
+			call.Error = json.Unmarshal(resp.Result, call.Reply)
+		}
+	}
+	for _, call := range pending {
+		call.Error = errors.New("jsonrpc2: missing response")
+	}
+	return nil
+}
+
+// Close closes the underlying connection.
+func (c *JSONRPCClient) Close() error {
+	return c.transport.Close()
+}
+
+func encodeParams(params interface{}) (json.RawMessage, error) {
+	if params == nil {
+		return nil, nil
+	}
+	// Structs and maps go by name; anything else, slices included, is the
+	// single positional param decodeParams expects.
+	switch reflect.Indirect(reflect.ValueOf(params)).Kind() {
+	case reflect.Struct, reflect.Map:
+		return json.Marshal(params)
+	}
+	return json.Marshal([]interface{}{params})
+}
+
+type jsonrpcStreamTransport struct {
+	conn io.ReadWriteCloser
+	enc  *json.Encoder
+	dec  *json.Decoder
+	// stale is set when a caller stopped waiting for a reply. It yields the
+	// result of reading that reply, which must be discarded before the
+	// next one is read.
+	stale <-chan error
+}
+
+func (t *jsonrpcStreamTransport) roundTrip(ctx context.Context, msg interface{}, expectReply bool) (json.RawMessage, error) {
+	if err := t.enc.Encode(msg); err != nil {
+		return nil, err
+	}
+	if !expectReply {
+		return nil, nil
+	}
+	var reply json.RawMessage
+	if ctx.Done() == nil && t.stale == nil {
+		err := t.dec.Decode(&reply)
+		return reply, err
+	}
+
+	// Read in the background so that the caller can give up on ctx; the
+	// next round trip then picks up where this read left off.
+	stale, done := t.stale, make(chan error, 1)
+	t.stale = nil
+	go func() {
+		if stale != nil {
+			if err := <-stale; err != nil {
+				done <- err
+				return
+			}
+		}
+		done <- t.dec.Decode(&reply)
+	}()
+	select {
+	case err := <-done:
+		return reply, err
+	case <-ctx.Done():
+		t.stale = done
+		return nil, ctx.Err()
+	}
+}
+
+func (t *jsonrpcStreamTransport) Close() error {
+	return t.conn.Close()
+}
+
+type jsonrpcHTTPTransport struct {
+	client *http.Client
+	url    string
+}
+
+func (t *jsonrpcHTTPTransport) roundTrip(ctx context.Context, msg interface{}, expectReply bool) (json.RawMessage, error) {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, t.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := t.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	reply, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusNoContent {
+		return nil, fmt.Errorf("jsonrpc2: HTTP %d: %s", res.StatusCode, strings.TrimSpace(string(reply)))
+	}
+	return reply, nil
+}
+
+func (t *jsonrpcHTTPTransport) Close() error {
+	return nil
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var jsonrpc2Server *JSONRPCServer
+
+const jsonrpc2HTTPURL = "http://127.0.0.1:8087/"
+
+func startJSONRPC2Server() {
+	if jsonrpc2Server != nil {
+		return
+	}
+	jsonrpc2Server = NewJSONRPCServer()
+	if err := jsonrpc2Server.Register(new(AgentHandler)); err != nil {
+		panic(err)
+	}
+	if err := jsonrpc2Server.RegisterName("Arithmetic", new(Arith)); err != nil {
+		panic(err)
+	}
+
+	listener, err := net.Listen("tcp", ":8086")
+	if err != nil {
+		panic(err)
+	}
+	go jsonrpc2Server.Accept(listener)
+
+	httpListener, err := net.Listen("tcp", ":8087")
+	if err != nil {
+		panic(err)
+	}
+	go func() {
+		err := http.Serve(httpListener, jsonrpc2Server)
+		if err != nil {
+			panic(err)
+		}
+	}()
+}
+
+// rawJSONRPC2 sends req verbatim over TCP and returns the raw reply line.
+func rawJSONRPC2(t *testing.T, conn net.Conn, r *bufio.Reader, req string) string {
+	if _, err := conn.Write([]byte(req + "\n")); err != nil {
+		t.Fatal(err)
+	}
+	line, err := r.ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.TrimSpace(line)
+}
+
+func TestJSONRPC2Wire(t *testing.T) {
+	startJSONRPC2Server()
+
+	conn, err := net.Dial("tcp", "127.0.0.1:8086")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	for _, tc := range []struct {
+		req, want string
+	}{
+		{
+			`{"jsonrpc":"2.0","method":"Arithmetic.Multiply","params":{"A":6,"B":7},"id":1}`,
+			`{"jsonrpc":"2.0","result":42,"id":1}`,
+		},
+		{
+			`{"jsonrpc":"2.0","method":"Arithmetic.Divide","params":[{"A":7,"B":2}],"id":"a"}`,
+			`{"jsonrpc":"2.0","result":{"Quo":3,"Rem":1},"id":"a"}`,
+		},
+		{
+			`{"jsonrpc":"2.0","method":"Arithmetic.Divide","params":{"A":1,"B":0},"id":2}`,
+			`{"jsonrpc":"2.0","error":{"code":-32000,"message":"divide by zero","data":{"code":3}},"id":2}`,
+		},
+		{
+			`{"jsonrpc":"2.0","method":"Arithmetic.Missing","id":3}`,
+			`{"jsonrpc":"2.0","error":{"code":-32601,"message":"method not found: Arithmetic.Missing"},"id":3}`,
+		},
+		{
+			`{"jsonrpc":"2.0","method":"Arithmetic.Multiply","params":[1,2],"id":4}`,
+			`{"jsonrpc":"2.0","error":{"code":-32602,"message":"expected 1 positional param, got 2"},"id":4}`,
+		},
+		{
+			// A null id is not a notification and is echoed back.
+			`{"jsonrpc":"2.0","method":"Arithmetic.Multiply","params":{"A":2,"B":2},"id":null}`,
+			`{"jsonrpc":"2.0","result":4,"id":null}`,
+		},
+		{
+			`{"jsonrpc":"1.0","method":"Arithmetic.Multiply","id":5}`,
+			`{"jsonrpc":"2.0","error":{"code":-32600,"message":"invalid request"},"id":5}`,
+		},
+		{
+			`[]`,
+			`{"jsonrpc":"2.0","error":{"code":-32600,"message":"empty batch"},"id":null}`,
+		},
+		{
+			// The notification in the middle of the batch gets no reply,
+			// and neither does the standalone notification sent first.
+			`{"jsonrpc":"2.0","method":"Arithmetic.Multiply","params":{"A":1,"B":1}}` + "\n" +
+				`[{"jsonrpc":"2.0","method":"Arithmetic.Multiply","params":{"A":2,"B":3},"id":6},` +
+				`{"jsonrpc":"2.0","method":"Arithmetic.Multiply","params":{"A":2,"B":3}},` +
+				`{"jsonrpc":"2.0","method":"Arithmetic.Divide","params":{"A":2,"B":0},"id":7}]`,
+			`[{"jsonrpc":"2.0","result":6,"id":6},{"jsonrpc":"2.0","error":{"code":-32000,"message":"divide by zero","data":{"code":3}},"id":7}]`,
+		},
+	} {
+		if got := rawJSONRPC2(t, conn, r, tc.req); got != tc.want {
+			t.Errorf("%s\ngot  %s\nwant %s", tc.req, got, tc.want)
+		}
+	}
+}
+
+func TestJSONRPC2Params(t *testing.T) {
+	for _, tc := range []struct {
+		params interface{}
+		want   string
+	}{
+		{"1", `["1"]`},
+		{[]string{"a", "b"}, `[["a","b"]]`},
+		{[2]int{1, 2}, `[[1,2]]`},
+		{[]byte("ab"), `["YWI="]`},
+		{&Args{6, 7}, `{"A":6,"B":7}`},
+		{map[string]int{"A": 1}, `{"A":1}`},
+	} {
+		out, err := encodeParams(tc.params)
+		if err != nil || string(out) != tc.want {
+			t.Errorf("encodeParams(%v) = %s, %v, want %s", tc.params, out, err, tc.want)
+			continue
+		}
+		got := reflect.New(reflect.TypeOf(tc.params))
+		if err := decodeParams(out, got.Interface()); err != nil {
+			t.Errorf("decodeParams(%s): %v", out, err)
+		} else if !reflect.DeepEqual(got.Elem().Interface(), tc.params) {
+			t.Errorf("decodeParams(%s) = %v, want %v", out, got.Elem(), tc.params)
+		}
+	}
+}
+
+func testJSONRPC2Client(t *testing.T, client *JSONRPCClient) {
+	var reply AgentData
+	if err := client.Call("AgentHandler.Serve", "1", &reply); err != nil {
+		t.Fatal(err)
+	}
+	if reply.Hostname != generateObject().Hostname {
+		t.Errorf("unexpected reply %+v", reply)
+	}
+
+	err := client.Call("Arithmetic.Divide", &Args{1, 0}, new(Quotient))
+	if rpcErr, ok := err.(*JSONRPCError); !ok || rpcErr.Code != JSONRPCServerError || rpcErr.Message != "divide by zero" {
+		t.Errorf("Divide by zero: got %v", err)
+	}
+
+	if err := client.Notify("Arithmetic.Multiply", &Args{1, 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	var product int
+	var quo Quotient
+	calls := []*JSONRPCCall{
+		{Method: "Arithmetic.Multiply", Params: &Args{6, 7}, Reply: &product},
+		{Method: "Arithmetic.Multiply", Params: &Args{6, 7}, Notification: true},
+		{Method: "Arithmetic.Divide", Params: &Args{7, 2}, Reply: &quo},
+		{Method: "Arithmetic.Divide", Params: &Args{7, 0}, Reply: &quo},
+	}
+	if err := client.Batch(calls...); err != nil {
+		t.Fatal(err)
+	}
+	if calls[0].Error != nil || product != 42 {
+		t.Errorf("Multiply: got %d, %v", product, calls[0].Error)
+	}
+	if calls[2].Error != nil || quo != (Quotient{3, 1}) {
+		t.Errorf("Divide: got %+v, %v", quo, calls[2].Error)
+	}
+	if calls[3].Error == nil {
+		t.Error("Divide by zero in batch succeeded")
+	}
+}
+
+func TestJSONRPC2Client(t *testing.T) {
+	startJSONRPC2Server()
+
+	client, err := DialJSONRPC("tcp", "127.0.0.1:8086")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	testJSONRPC2Client(t, client)
+}
+
+func TestJSONRPC2HTTPClient(t *testing.T) {
+	startJSONRPC2Server()
+
+	testJSONRPC2Client(t, NewJSONRPCHTTPClient(http.DefaultClient, jsonrpc2HTTPURL))
+
+	res, err := http.Post(jsonrpc2HTTPURL, "application/json", strings.NewReader(`{"jsonrpc":"2.0","method":"Arithmetic.Multiply","params":{"A":1,"B":1}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusNoContent {
+		t.Errorf("notification: got status %d, want %d", res.StatusCode, http.StatusNoContent)
+	}
+
+	res, err = http.Post(jsonrpc2HTTPURL, "application/json", strings.NewReader(`{"jsonrpc":`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var resp jsonrpcResponse
+	err = json.NewDecoder(res.Body).Decode(&resp)
+	res.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Error == nil || resp.Error.Code != JSONRPCParseError {
+		t.Errorf("malformed request: got %+v", resp)
+	}
+}
+
+func benchmarkJSONRPC2(b *testing.B, client *JSONRPCClient, batchSize int) {
+	calls := make([]*JSONRPCCall, batchSize)
+	replies := make([]AgentData, batchSize)
+	for i := range calls {
+		calls[i] = &JSONRPCCall{Method: "AgentHandler.Serve", Reply: &replies[i]}
+	}
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n += batchSize {
+		batch := calls
+		if b.N-n < batchSize {
+			batch = calls[:b.N-n]
+		}
+		for i, call := range batch {
+			call.Params = strconv.Itoa(n + i)
+		}
+		err := client.Batch(batch...)
+		if err != nil {
+			panic(err)
+		}
+		for _, call := range batch {
+			if call.Error != nil {
+				panic(call.Error)
+			}
+		}
+	}
+}
+
+func BenchmarkJSONRPC2(b *testing.B) {
+	startJSONRPC2Server()
+
+	client, err := DialJSONRPC("tcp", "127.0.0.1:8086")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer client.Close()
+
+	benchmarkJSONRPC2(b, client, 1)
+}
+
+func BenchmarkJSONRPC2Batch(b *testing.B) {
+	startJSONRPC2Server()
+
+	for _, size := range []int{10, 100} {
+		b.Run(strconv.Itoa(size), func(b *testing.B) {
+			client, err := DialJSONRPC("tcp", "127.0.0.1:8086")
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer client.Close()
+
+			benchmarkJSONRPC2(b, client, size)
+		})
+	}
+}
+
+func BenchmarkJSONRPC2HTTP(b *testing.B) {
+	startJSONRPC2Server()
+
+	benchmarkJSONRPC2(b, NewJSONRPCHTTPClient(&http.Client{}, jsonrpc2HTTPURL), 1)
+}
+
+func BenchmarkJSONRPC2HTTPBatch(b *testing.B) {
+	startJSONRPC2Server()
+
+	for _, size := range []int{10, 100} {
+		b.Run(strconv.Itoa(size), func(b *testing.B) {
+			benchmarkJSONRPC2(b, NewJSONRPCHTTPClient(&http.Client{}, jsonrpc2HTTPURL), size)
+		})
+	}
+}
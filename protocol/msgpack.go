@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// This file holds just enough of MessagePack (https://msgpack.org) to encode
+// the AgentData shape: maps, arrays, strings and signed integers.
+
+var errMsgpackShort = errors.New("msgpack: unexpected end of data")
+
+func appendMsgpackMapHeader(b []byte, n int) []byte {
+	switch {
+	case n < 16:
+		return append(b, 0x80|byte(n))
+	case n <= 0xffff:
+		return append(b, 0xde, byte(n>>8), byte(n))
+	}
+	return append(b, 0xdf, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+}
+
+func appendMsgpackArrayHeader(b []byte, n int) []byte {
+	switch {
+	case n < 16:
+		return append(b, 0x90|byte(n))
+	case n <= 0xffff:
+		return append(b, 0xdc, byte(n>>8), byte(n))
+	}
+	return append(b, 0xdd, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+}
+
+func appendMsgpackString(b []byte, s string) []byte {
+	n := len(s)
+	switch {
+	case n < 32:
+		b = append(b, 0xa0|byte(n))
+	case n <= 0xff:
+		b = append(b, 0xd9, byte(n))
+	case n <= 0xffff:
+		b = append(b, 0xda, byte(n>>8), byte(n))
+	default:
+		b = append(b, 0xdb, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+	}
+	return append(b, s...)
+}
+
+func appendMsgpackInt(b []byte, v int64) []byte {
+	switch {
+	case v >= 0 && v < 128:
+		return append(b, byte(v))
+	case v < 0 && v >= -32:
+		return append(b, byte(v))
+	case v >= -1<<31 && v < 1<<31:
+		return append(b, 0xd2, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+	}
+	b = append(b, 0xd3)
+	return append(b, byte(v>>56), byte(v>>48), byte(v>>40), byte(v>>32), byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+// msgpackReader decodes values appended by the appendMsgpack* functions.
+type msgpackReader struct {
+	b []byte
+}
+
+func (r *msgpackReader) next(n int) ([]byte, error) {
+	if len(r.b) < n {
+		return nil, errMsgpackShort
+	}
+	out := r.b[:n]
+	r.b = r.b[n:]
+	return out, nil
+}
+
+func (r *msgpackReader) length(fix, mask byte, c8, c16, c32 byte) (int, error) {
+	head, err := r.next(1)
+	if err != nil {
+		return 0, err
+	}
+	c := head[0]
+	if c&^mask == fix {
+		return int(c & mask), nil
+	}
+	var size int
+	switch {
+	case c == c8 && c8 != 0:
+		size = 1
+	case c == c16:
+		size = 2
+	case c == c32:
+		size = 4
+	default:
+		return 0, errors.New("msgpack: unexpected type byte")
+	}
+	raw, err := r.next(size)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, v := range raw {
+		n = n<<8 | int(v)
+	}
+	return n, nil
+}
+
+func (r *msgpackReader) readMapHeader() (int, error) {
+	// There is no map8 type.
+	return r.length(0x80, 0x0f, 0x00, 0xde, 0xdf)
+}
+
+func (r *msgpackReader) readArrayHeader() (int, error) {
+	return r.length(0x90, 0x0f, 0x00, 0xdc, 0xdd)
+}
+
+func (r *msgpackReader) readString() (string, error) {
+	n, err := r.length(0xa0, 0x1f, 0xd9, 0xda, 0xdb)
+	if err != nil {
+		return "", err
+	}
+	s, err := r.next(n)
+	if err != nil {
+		return "", err
+	}
+	return string(s), nil
+}
+
+func (r *msgpackReader) readInt() (int64, error) {
+	head, err := r.next(1)
+	if err != nil {
+		return 0, err
+	}
+	c := head[0]
+	switch {
+	case c < 0x80, c >= 0xe0:
+		return int64(int8(c)), nil
+	case c == 0xd2:
+		raw, err := r.next(4)
+		if err != nil {
+			return 0, err
+		}
+		return int64(int32(binary.BigEndian.Uint32(raw))), nil
+	case c == 0xd3:
+		raw, err := r.next(8)
+		if err != nil {
+			return 0, err
+		}
+		return int64(binary.BigEndian.Uint64(raw)), nil
+	}
+	return 0, errors.New("msgpack: unexpected integer type byte")
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+// negotiateContentType picks the offer that best matches an Accept header.
+// Each offer takes the q-value of the most specific media range matching it,
+// so "application/json;q=0, */*" excludes JSON only; ties go to the earlier
+// offer. The first offer is used when accept is empty. It returns false if
+// nothing is acceptable.
+func negotiateContentType(accept string, offers []string) (string, bool) {
+	if strings.TrimSpace(accept) == "" {
+		return offers[0], true
+	}
+
+	type mediaRange struct {
+		pattern string
+		q       float64
+	}
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		r := mediaRange{pattern: strings.ToLower(strings.TrimSpace(params[0])), q: 1}
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if v, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					r.q = v
+				}
+			}
+		}
+		ranges = append(ranges, r)
+	}
+
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		q, specificity := 0.0, -1
+		for _, r := range ranges {
+			if s := mediaTypeMatch(r.pattern, offer); s > specificity {
+				q, specificity = r.q, s
+			}
+		}
+		if q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best, best != ""
+}
+
+// mediaTypeMatch reports how specifically pattern matches mediaType: 2 for an
+// exact match, 1 for "type/*", 0 for "*/*" and -1 for no match.
+func mediaTypeMatch(pattern, mediaType string) int {
+	switch {
+	case pattern == mediaType:
+		return 2
+	case pattern == "*/*":
+		return 0
+	case strings.HasSuffix(pattern, "/*") && strings.HasPrefix(mediaType, pattern[:len(pattern)-1]):
+		return 1
+	}
+	return -1
+}
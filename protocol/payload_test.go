@@ -0,0 +1,244 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"strings"
+	"testing"
+
+	pb "github.com/evaluate_serde_protocol/protocol/agent"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+)
+
+// payloadPreset describes the size of an uploaded AgentData.
+type payloadPreset struct {
+	name      string
+	statusLen int
+	lsns      int
+}
+
+var payloadPresets = []payloadPreset{
+	{"small", 11, 2},
+	{"medium", 256, 100},
+	{"large", 4096, 10000},
+}
+
+func generatePayload(preset payloadPreset) *AgentData {
+	obj := generateObject()
+	obj.Status = strings.Repeat("x", preset.statusLen)
+	obj.Lsns = make([]string, preset.lsns)
+	for i := range obj.Lsns {
+		obj.Lsns[i] = fmt.Sprintf("16/%08X", 0xB374D010+i*8)
+	}
+	return obj
+}
+
+// runUpload runs fn once per payload preset. Throughput is reported against
+// the protobuf encoding of the payload so that transports are comparable.
+func runUpload(b *testing.B, fn func(b *testing.B, obj *AgentData)) {
+	for _, preset := range payloadPresets {
+		b.Run(preset.name, func(b *testing.B) {
+			obj := generatePayload(preset)
+			b.SetBytes(int64(proto.Size(toAgentProto(obj))))
+			b.ResetTimer()
+			fn(b, obj)
+		})
+	}
+}
+
+func uploadRPC(b *testing.B, client *rpc.Client, obj *AgentData) {
+	var reply StatusAck
+	for n := 0; n < b.N; n++ {
+		err := client.Call("AgentHandler.ReportStatus", obj, &reply)
+		if err != nil {
+			panic(err)
+		}
+		if reply.ReceivedLsns != int64(len(obj.Lsns)) {
+			panic("short upload")
+		}
+	}
+}
+
+func postStatus(client *http.Client, body []byte) StatusAck {
+	res, err := client.Post("http://127.0.0.1:8080/", jsonMediaType, bytes.NewReader(body))
+	if err != nil {
+		panic(err)
+	}
+	out, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		panic(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		panic("request failed: " + string(out))
+	}
+
+	var ack StatusAck
+	err = json.Unmarshal(out, &ack)
+	if err != nil {
+		panic(err)
+	}
+	return ack
+}
+
+func TestReportStatus(t *testing.T) {
+	startTCPRPCServer()
+	startJSONRPCServer()
+	startHTTPRPCServer()
+	startHTTPServer()
+	startGRPCServer()
+
+	obj := generatePayload(payloadPresets[1])
+	want := int64(len(obj.Lsns))
+
+	tcpClient, err := rpc.Dial("tcp", "127.0.0.1:8081")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tcpClient.Close()
+	jsonClient, err := jsonrpc.Dial("tcp", "127.0.0.1:8082")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer jsonClient.Close()
+	httpRPCClient, err := rpc.DialHTTP("tcp", "127.0.0.1:8083")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer httpRPCClient.Close()
+
+	for name, client := range map[string]*rpc.Client{"tcp": tcpClient, "jsonrpc": jsonClient, "httprpc": httpRPCClient} {
+		var reply StatusAck
+		if err := client.Call("AgentHandler.ReportStatus", obj, &reply); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if reply.ReceivedLsns != want {
+			t.Errorf("%s: received %d lsns, want %d", name, reply.ReceivedLsns, want)
+		}
+	}
+
+	for _, mediaType := range []string{jsonMediaType, protobufMediaType, gobMediaType, msgpackMediaType} {
+		body, err := marshalAgentData(mediaType, obj)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, out := postWeb(http.DefaultClient, "http://127.0.0.1:8080/", mediaType, body)
+		if res.StatusCode != http.StatusOK {
+			t.Fatalf("POST %s: status %d: %s", mediaType, res.StatusCode, out)
+		}
+		var ack StatusAck
+		if err := json.Unmarshal(out, &ack); err != nil {
+			t.Fatal(err)
+		}
+		if ack.ReceivedLsns != want {
+			t.Errorf("POST %s: received %d lsns, want %d", mediaType, ack.ReceivedLsns, want)
+		}
+	}
+	res, _ := postWeb(http.DefaultClient, "http://127.0.0.1:8080/", "text/plain", []byte("x"))
+	if res.StatusCode != http.StatusUnsupportedMediaType {
+		t.Errorf("POST text/plain: got status %d, want %d", res.StatusCode, http.StatusUnsupportedMediaType)
+	}
+
+	conn, err := grpc.Dial("127.0.0.1:8084", grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	ack, err := pb.NewAgentClient(conn).ReportAgentProto(context.Background(), toAgentProto(obj))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ack.ReceivedLsns != want {
+		t.Errorf("grpc: received %d lsns, want %d", ack.ReceivedLsns, want)
+	}
+}
+
+func BenchmarkTCPRPCUpload(b *testing.B) {
+	startTCPRPCServer()
+
+	client, err := rpc.Dial("tcp", "127.0.0.1:8081")
+	if err != nil {
+		panic(err)
+	}
+	defer client.Close()
+
+	runUpload(b, func(b *testing.B, obj *AgentData) {
+		uploadRPC(b, client, obj)
+	})
+}
+
+func BenchmarkJSONRPCUpload(b *testing.B) {
+	startJSONRPCServer()
+
+	client, err := jsonrpc.Dial("tcp", "127.0.0.1:8082")
+	if err != nil {
+		panic(err)
+	}
+	defer client.Close()
+
+	runUpload(b, func(b *testing.B, obj *AgentData) {
+		uploadRPC(b, client, obj)
+	})
+}
+
+func BenchmarkHTTPRPCUpload(b *testing.B) {
+	startHTTPRPCServer()
+
+	client, err := rpc.DialHTTP("tcp", "127.0.0.1:8083")
+	if err != nil {
+		panic(err)
+	}
+	defer client.Close()
+
+	runUpload(b, func(b *testing.B, obj *AgentData) {
+		uploadRPC(b, client, obj)
+	})
+}
+
+func BenchmarkHTTPUpload(b *testing.B) {
+	startHTTPServer()
+
+	client := &http.Client{}
+
+	runUpload(b, func(b *testing.B, obj *AgentData) {
+		for n := 0; n < b.N; n++ {
+			body, err := json.Marshal(obj)
+			if err != nil {
+				panic(err)
+			}
+			if postStatus(client, body).ReceivedLsns != int64(len(obj.Lsns)) {
+				panic("short upload")
+			}
+		}
+	})
+}
+
+func BenchmarkGRPCUpload(b *testing.B) {
+	startGRPCServer()
+
+	conn, err := grpc.Dial(":8084", grpc.WithInsecure())
+	if err != nil {
+		panic(err)
+	}
+	defer conn.Close()
+	client := pb.NewAgentClient(conn)
+
+	runUpload(b, func(b *testing.B, obj *AgentData) {
+		for n := 0; n < b.N; n++ {
+			ack, err := client.ReportAgentProto(context.Background(), toAgentProto(obj))
+			if err != nil {
+				panic(err)
+			}
+			if ack.ReceivedLsns != int64(len(obj.Lsns)) {
+				panic("short upload")
+			}
+		}
+	})
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	pb "github.com/evaluate_serde_protocol/protocol/agent"
+	"google.golang.org/grpc"
+)
+
+var pipelineWindows = flag.String("pipeline.windows", "1,8,64", "comma-separated numbers of outstanding calls per connection for the pipelined benchmarks")
+
+func parsePipelineWindows() []int {
+	var windows []int
+	for _, s := range strings.Split(*pipelineWindows, ",") {
+		window, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil || window < 1 {
+			panic("invalid -pipeline.windows value " + strconv.Quote(s))
+		}
+		windows = append(windows, window)
+	}
+	return windows
+}
+
+// runPipelined runs fn for every window in -pipeline.windows. fn must
+// complete b.N calls and return the summed latency of all calls.
+func runPipelined(b *testing.B, fn func(b *testing.B, window int) time.Duration) {
+	for _, window := range parsePipelineWindows() {
+		b.Run("window="+strconv.Itoa(window), func(b *testing.B) {
+			b.ResetTimer()
+			start := time.Now()
+			latency := fn(b, window)
+			elapsed := time.Since(start)
+			b.StopTimer()
+
+			b.ReportMetric(float64(b.N)/elapsed.Seconds(), "calls/s")
+			b.ReportMetric(float64(latency.Nanoseconds())/float64(b.N), "ns/call-latency")
+		})
+	}
+}
+
+// pipelineRPC keeps window Client.Go calls outstanding on client.
+func pipelineRPC(b *testing.B, client *rpc.Client, window int) time.Duration {
+	done := make(chan *rpc.Call, window)
+	started := make(map[*rpc.Call]time.Time, window)
+	var latency time.Duration
+	issued, inflight := 0, 0
+	for completed := 0; completed < b.N; completed++ {
+		for inflight < window && issued < b.N {
+			call := client.Go("AgentHandler.Serve", strconv.Itoa(issued), new(AgentData), done)
+			started[call] = time.Now()
+			issued++
+			inflight++
+		}
+		call := <-done
+		if call.Error != nil {
+			panic(call.Error)
+		}
+		latency += time.Since(started[call])
+		delete(started, call)
+		inflight--
+	}
+	return latency
+}
+
+func BenchmarkTCPRPCPipelined(b *testing.B) {
+	startTCPRPCServer()
+
+	runPipelined(b, func(b *testing.B, window int) time.Duration {
+		client, err := rpc.Dial("tcp", "127.0.0.1:8081")
+		if err != nil {
+			panic(err)
+		}
+		defer client.Close()
+		return pipelineRPC(b, client, window)
+	})
+}
+
+func BenchmarkJSONRPCPipelined(b *testing.B) {
+	startJSONRPCServer()
+
+	runPipelined(b, func(b *testing.B, window int) time.Duration {
+		client, err := jsonrpc.Dial("tcp", "127.0.0.1:8082")
+		if err != nil {
+			panic(err)
+		}
+		defer client.Close()
+		return pipelineRPC(b, client, window)
+	})
+}
+
+func BenchmarkHTTPRPCPipelined(b *testing.B) {
+	startHTTPRPCServer()
+
+	runPipelined(b, func(b *testing.B, window int) time.Duration {
+		client, err := rpc.DialHTTP("tcp", "127.0.0.1:8083")
+		if err != nil {
+			panic(err)
+		}
+		defer client.Close()
+		return pipelineRPC(b, client, window)
+	})
+}
+
+// BenchmarkHTTPPipelined writes up to window requests ahead of the responses
+// on a single HTTP/1.1 connection. net/http's client never pipelines, so the
+// requests are written to the connection directly.
+func BenchmarkHTTPPipelined(b *testing.B) {
+	startHTTPServer()
+
+	request := []byte("GET / HTTP/1.1\r\nHost: 127.0.0.1:8080\r\n\r\n")
+	runPipelined(b, func(b *testing.B, window int) time.Duration {
+		conn, err := net.Dial("tcp", "127.0.0.1:8080")
+		if err != nil {
+			panic(err)
+		}
+		defer conn.Close()
+
+		// Responses arrive in request order, so a FIFO of send times is
+		// enough to match them up. Its capacity bounds the window.
+		sent := make(chan time.Time, window)
+		go func() {
+			w := bufio.NewWriter(conn)
+			for n := 0; n < b.N; n++ {
+				sent <- time.Now()
+				w.Write(request)
+				if len(sent) == cap(sent) || n == b.N-1 {
+					if err := w.Flush(); err != nil {
+						return
+					}
+				}
+			}
+			w.Flush()
+		}()
+
+		var latency time.Duration
+		r := bufio.NewReader(conn)
+		for n := 0; n < b.N; n++ {
+			res, err := http.ReadResponse(r, nil)
+			if err != nil {
+				panic(err)
+			}
+			if res.StatusCode != http.StatusOK {
+				panic("request failed")
+			}
+			if _, err := io.Copy(ioutil.Discard, res.Body); err != nil {
+				panic(err)
+			}
+			res.Body.Close()
+			latency += time.Since(<-sent)
+		}
+		return latency
+	})
+}
+
+// BenchmarkGRPCMultiplexed keeps window concurrent calls in flight on a
+// single HTTP/2 connection.
+func BenchmarkGRPCMultiplexed(b *testing.B) {
+	startGRPCServer()
+
+	runPipelined(b, func(b *testing.B, window int) time.Duration {
+		conn, err := grpc.Dial("127.0.0.1:8084", grpc.WithInsecure())
+		if err != nil {
+			panic(err)
+		}
+		defer conn.Close()
+		client := pb.NewAgentClient(conn)
+
+		var next, latency int64
+		var wg sync.WaitGroup
+		for i := 0; i < window; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for {
+					n := atomic.AddInt64(&next, 1)
+					if n > int64(b.N) {
+						return
+					}
+					start := time.Now()
+					_, err := client.ServeAgentProto(context.Background(), &pb.AgentRequest{Data: strconv.FormatInt(n, 10)})
+					if err != nil {
+						panic(err)
+					}
+					atomic.AddInt64(&latency, int64(time.Since(start)))
+				}
+			}()
+		}
+		wg.Wait()
+		return time.Duration(latency)
+	})
+}
@@ -1,10 +1,11 @@
 package main
 
 import (
-	"errors"
 	"fmt"
 	"net/http"
 	"net/rpc"
+
+	"google.golang.org/grpc/codes"
 )
 
 type Args struct {
@@ -17,6 +18,9 @@ type Quotient struct {
 
 type Arith int
 
+// ErrDivideByZero is returned by Arith.Divide when the divisor is zero.
+var ErrDivideByZero = Errorf(codes.InvalidArgument, "divide by zero")
+
 func (t *Arith) Multiply(args *Args, reply *int) error {
 	*reply = args.A * args.B
 	return nil
@@ -24,18 +28,18 @@ func (t *Arith) Multiply(args *Args, reply *int) error {
 
 func (t *Arith) Divide(args *Args, quo *Quotient) error {
 	if args.B == 0 {
-		return errors.New("divide by zero")
+		return ErrDivideByZero
 	}
 	quo.Quo = args.A / args.B
 	quo.Rem = args.A % args.B
 	return nil
 }
 
-func registerArith(server *rpc.Server, arith Arith) {
+func registerArith(server *rpc.Server, arith *Arith) error {
 	// registers Arith interface by name of `Arithmetic`.
 	// If you want this name to be same as the type name, you
 	// can use server.Register instead.
-	server.RegisterName("Arithmetic", arith)
+	return server.RegisterName("Arithmetic", arith)
 }
 
 
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// problemMediaType is the media type of RFC 7807 problem details.
+const problemMediaType = "application/problem+json"
+
+// StatusError is the error model shared by every transport. Handlers return
+// a *StatusError once and each transport translates it:
+//
+//   - net/rpc sends the string returned by Error, which ToCallError parses
+//     back from the rpc.ServerError the client receives;
+//   - JSON-RPC 2.0 sends the error object returned by JSONRPCError;
+//   - HTTP handlers write it with WriteError as an application/problem+json
+//     body;
+//   - gRPC picks up GRPCStatus, which carries Details as a
+//     google.protobuf.Struct status detail.
+type StatusError struct {
+	Code    codes.Code
+	Message string
+	// Details are machine-readable key/value pairs, such as the name of an
+	// invalid field.
+	Details map[string]string
+}
+
+// Errorf returns a *StatusError with a formatted message.
+func Errorf(code codes.Code, format string, a ...interface{}) *StatusError {
+	return &StatusError{Code: code, Message: fmt.Sprintf(format, a...)}
+}
+
+// WithDetail returns a copy of e with key set to value in its details.
+func (e *StatusError) WithDetail(key, value string) *StatusError {
+	details := make(map[string]string, len(e.Details)+1)
+	for k, v := range e.Details {
+		details[k] = v
+	}
+	details[key] = value
+	return &StatusError{Code: e.Code, Message: e.Message, Details: details}
+}
+
+const (
+	statusErrorCode    = "code = "
+	statusErrorDesc    = " desc = "
+	statusErrorDetails = " details = "
+)
+
+// Error formats e as "code = <code> desc = <message>", followed by
+// " details = <JSON object>" if e has details. This is also the form sent
+// over net/rpc, which only transports strings.
+func (e *StatusError) Error() string {
+	s := statusErrorCode + e.Code.String() + statusErrorDesc + e.Message
+	if len(e.Details) > 0 {
+		details, _ := json.Marshal(e.Details)
+		s += statusErrorDetails + string(details)
+	}
+	return s
+}
+
+// parseStatusError is the inverse of StatusError.Error.
+func parseStatusError(s string) (*StatusError, bool) {
+	if !strings.HasPrefix(s, statusErrorCode) {
+		return nil, false
+	}
+	s = s[len(statusErrorCode):]
+	i := strings.Index(s, statusErrorDesc)
+	if i < 0 {
+		return nil, false
+	}
+	code, ok := codesByName[s[:i]]
+	if !ok {
+		return nil, false
+	}
+	e := &StatusError{Code: code, Message: s[i+len(statusErrorDesc):]}
+	if j := strings.LastIndex(e.Message, statusErrorDetails); j >= 0 {
+		var details map[string]string
+		if json.Unmarshal([]byte(e.Message[j+len(statusErrorDetails):]), &details) == nil {
+			e.Message, e.Details = e.Message[:j], details
+		}
+	}
+	return e, true
+}
+
+// codesByName maps the output of codes.Code.String back to the code.
+var codesByName = func() map[string]codes.Code {
+	m := make(map[string]codes.Code)
+	for c := codes.OK; c <= codes.Unauthenticated; c++ {
+		m[c.String()] = c
+	}
+	return m
+}()
+
+// GRPCStatus converts e to a gRPC status, which makes grpc-go send it as is
+// when a handler returns e.
+func (e *StatusError) GRPCStatus() *status.Status {
+	s := status.New(e.Code, e.Message)
+	if len(e.Details) == 0 {
+		return s
+	}
+	fields := make(map[string]*structpb.Value, len(e.Details))
+	for k, v := range e.Details {
+		fields[k] = &structpb.Value{Kind: &structpb.Value_StringValue{StringValue: v}}
+	}
+	withDetails, err := s.WithDetails(&structpb.Struct{Fields: fields})
+	if err != nil {
+		return s
+	}
+	return withDetails
+}
+
+// toGRPCStatus converts err to a gRPC status, unwrapping a *StatusError
+// that status.Convert would report as codes.Unknown.
+func toGRPCStatus(err error) *status.Status {
+	var e *StatusError
+	if errors.As(err, &e) {
+		return e.GRPCStatus()
+	}
+	return status.Convert(err)
+}
+
+// statusErrorData is the data member of JSON-RPC errors converted from a
+// StatusError.
+type statusErrorData struct {
+	Code    codes.Code        `json:"code"`
+	Details map[string]string `json:"details,omitempty"`
+}
+
+// JSONRPCError converts e to a JSON-RPC 2.0 error object with the
+// JSONRPCServerError code, keeping the gRPC code and details in its data
+// member. The standard codes are reserved for failures of the protocol
+// itself, such as params that do not decode, so an application error never
+// uses them even when its gRPC code is InvalidArgument or Unimplemented.
+func (e *StatusError) JSONRPCError() *JSONRPCError {
+	return &JSONRPCError{Code: JSONRPCServerError, Message: e.Message, Data: statusErrorData{Code: e.Code, Details: e.Details}}
+}
+
+// problem is an RFC 7807 problem details object extended with the code and
+// details of a StatusError.
+type problem struct {
+	Type    string            `json:"type"`
+	Title   string            `json:"title"`
+	Status  int               `json:"status"`
+	Detail  string            `json:"detail"`
+	Code    codes.Code        `json:"code"`
+	Details map[string]string `json:"details,omitempty"`
+}
+
+// WriteError writes err as an application/problem+json response with the
+// HTTP status matching its code. Errors that do not wrap a *StatusError are
+// reported as codes.Unknown.
+func WriteError(w http.ResponseWriter, err error) {
+	e := toStatusError(err)
+	writeProblem(w, HTTPStatusFromCode(e.Code), e)
+}
+
+// writeProblem writes e with an explicit HTTP status, for failures such as
+// 406 Not Acceptable that have no gRPC code of their own.
+func writeProblem(w http.ResponseWriter, statusCode int, e *StatusError) {
+	out, _ := json.Marshal(problem{
+		Type:    "about:blank",
+		Title:   http.StatusText(statusCode),
+		Status:  statusCode,
+		Detail:  e.Message,
+		Code:    e.Code,
+		Details: e.Details,
+	})
+	w.Header().Set("Content-Type", problemMediaType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(statusCode)
+	w.Write(out)
+}
+
+func toStatusError(err error) *StatusError {
+	var e *StatusError
+	if errors.As(err, &e) {
+		return e
+	}
+	return &StatusError{Code: codes.Unknown, Message: err.Error()}
+}
+
+// statusErrorFromProblem decodes a body written by WriteError.
+func statusErrorFromProblem(body []byte) (*StatusError, bool) {
+	var p problem
+	if err := json.Unmarshal(body, &p); err != nil || p.Status == 0 {
+		return nil, false
+	}
+	return &StatusError{Code: p.Code, Message: p.Detail, Details: p.Details}, true
+}
+
+// statusErrorFromJSONRPC recovers the StatusError a JSONRPCError was
+// converted from, if any.
+func statusErrorFromJSONRPC(e *JSONRPCError) (*StatusError, bool) {
+	if e.Data == nil {
+		return nil, false
+	}
+	raw, err := json.Marshal(e.Data)
+	if err != nil {
+		return nil, false
+	}
+	var data statusErrorData
+	if err := json.Unmarshal(raw, &data); err != nil || data.Code == codes.OK {
+		return nil, false
+	}
+	return &StatusError{Code: data.Code, Message: e.Message, Details: data.Details}, true
+}
+
+// statusErrorFromGRPC converts s, including any google.protobuf.Struct
+// details written by GRPCStatus.
+func statusErrorFromGRPC(s *status.Status) *StatusError {
+	e := &StatusError{Code: s.Code(), Message: s.Message()}
+	for _, detail := range s.Details() {
+		st, ok := detail.(*structpb.Struct)
+		if !ok {
+			continue
+		}
+		if e.Details == nil {
+			e.Details = make(map[string]string, len(st.Fields))
+		}
+		for k, v := range st.Fields {
+			e.Details[k] = v.GetStringValue()
+		}
+	}
+	return e
+}
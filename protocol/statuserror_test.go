@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"reflect"
+	"testing"
+
+	pb "github.com/evaluate_serde_protocol/protocol/agent"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestStatusErrorString(t *testing.T) {
+	for _, e := range []*StatusError{
+		Errorf(codes.NotFound, "no agent %q", "db1"),
+		Errorf(codes.InvalidArgument, "bad lsn: 1 details = 2").WithDetail("field", "lsns").WithDetail("index", "3"),
+		{Code: codes.OK},
+	} {
+		got, ok := parseStatusError(e.Error())
+		if !ok || !reflect.DeepEqual(got, e) {
+			t.Errorf("parseStatusError(%q) = %+v, %v, want %+v", e.Error(), got, ok, e)
+		}
+	}
+	if _, ok := parseStatusError("divide by zero"); ok {
+		t.Error("parsed a plain error message as a StatusError")
+	}
+}
+
+// errRejected is the StatusError rejectingAgent fails every call with.
+var errRejected = Errorf(codes.InvalidArgument, "hostname is required").WithDetail("field", "hostname")
+
+// rejectingAgent rejects every status upload with errRejected, on every
+// transport.
+type rejectingAgent struct {
+	pb.UnimplementedAgentServer
+}
+
+func (rejectingAgent) ReportStatus(arg *AgentData, reply *StatusAck) error {
+	return errRejected
+}
+
+func (rejectingAgent) ReportAgentProto(ctx context.Context, in *pb.AgentProto) (*pb.StatusAck, error) {
+	return nil, errRejected
+}
+
+func (rejectingAgent) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	WriteError(w, errRejected)
+}
+
+// TestStatusErrorRoundTrip uploads a status to rejectingAgent over every
+// transport and checks that the code, message and details of the
+// StatusError it returns survive.
+func TestStatusErrorRoundTrip(t *testing.T) {
+	obj := generateObject()
+	want := &CallError{
+		Code:    errRejected.Code,
+		Message: errRejected.Message,
+		Details: errRejected.Details,
+	}
+
+	rpcServer := rpc.NewServer()
+	if err := rpcServer.RegisterName("AgentHandler", &rejectingAgent{}); err != nil {
+		t.Fatal(err)
+	}
+	tcpListener, jsonListener, httpListener := listenLoopback(t), listenLoopback(t), listenLoopback(t)
+	defer tcpListener.Close()
+	defer jsonListener.Close()
+	defer httpListener.Close()
+	go rpcServer.Accept(tcpListener)
+	go acceptLoop(jsonListener, func(conn io.ReadWriteCloser) {
+		rpcServer.ServeCodec(jsonrpc.NewServerCodec(conn))
+	})
+	go http.Serve(httpListener, rpcServer)
+
+	rpcCall := func(client *rpc.Client, err error) error {
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer client.Close()
+		return client.Call("AgentHandler.ReportStatus", obj, new(StatusAck))
+	}
+	errs := map[string]error{
+		"TCPRPC":  rpcCall(rpc.Dial("tcp", tcpListener.Addr().String())),
+		"JSONRPC": rpcCall(jsonrpc.Dial("tcp", jsonListener.Addr().String())),
+		"HTTPRPC": rpcCall(rpc.DialHTTP("tcp", httpListener.Addr().String())),
+	}
+
+	jsonrpc2Server := NewJSONRPCServer()
+	if err := jsonrpc2Server.RegisterName("AgentHandler", &rejectingAgent{}); err != nil {
+		t.Fatal(err)
+	}
+	jsonrpc2Listener := listenLoopback(t)
+	defer jsonrpc2Listener.Close()
+	go jsonrpc2Server.Accept(jsonrpc2Listener)
+	jsonrpc2HTTPServer := httptest.NewServer(jsonrpc2Server)
+	defer jsonrpc2HTTPServer.Close()
+
+	jsonrpc2Client, err := DialJSONRPC("tcp", jsonrpc2Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer jsonrpc2Client.Close()
+	errs["JSONRPC2"] = jsonrpc2Client.Call("AgentHandler.ReportStatus", obj, new(StatusAck))
+	errs["JSONRPC2HTTP"] = NewJSONRPCHTTPClient(http.DefaultClient, jsonrpc2HTTPServer.URL).Call("AgentHandler.ReportStatus", obj, new(StatusAck))
+
+	addr, stop := startAgentGRPCServer(&rejectingAgent{})
+	defer stop()
+	conn, err := grpc.Dial(addr, grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	_, errs["GRPC"] = pb.NewAgentClient(conn).ReportAgentProto(context.Background(), toAgentProto(obj))
+
+	httpServer := httptest.NewServer(&rejectingAgent{})
+	defer httpServer.Close()
+	body, _ := json.Marshal(obj)
+	res, err := http.Post(httpServer.URL, jsonMediaType, bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	errs["HTTP"] = CheckHTTPResponse(res)
+
+	for name, err := range errs {
+		got := ToCallError(err)
+		if got == nil {
+			t.Errorf("%s: rejected upload succeeded", name)
+			continue
+		}
+		if got.Code != want.Code || got.Message != want.Message || !reflect.DeepEqual(got.Details, want.Details) {
+			t.Errorf("%s: got %+v, want %+v", name, got, want)
+		}
+	}
+
+	if s, _ := status.FromError(errs["GRPC"]); len(s.Details()) != 1 {
+		t.Errorf("GRPC: status carries %d details, want 1", len(s.Details()))
+	}
+	if httpErr, ok := errs["HTTP"].(*HTTPError); !ok || httpErr.StatusCode != http.StatusBadRequest || httpErr.ContentType != problemMediaType {
+		t.Errorf("HTTP: got %#v, want a 400 %s response", errs["HTTP"], problemMediaType)
+	}
+}
+
+func TestProblemStatus(t *testing.T) {
+	startHTTPServer()
+
+	res, body := getWithAccept(http.DefaultClient, "text/html")
+	if res.StatusCode != http.StatusNotAcceptable || res.Header.Get("Content-Type") != problemMediaType {
+		t.Fatalf("GET text/html: status %d, Content-Type %q", res.StatusCode, res.Header.Get("Content-Type"))
+	}
+	e, ok := statusErrorFromProblem(body)
+	if !ok || e.Code != codes.InvalidArgument {
+		t.Errorf("GET text/html: problem %s decoded to %+v", body, e)
+	}
+}
+
+func TestWriteErrorWrapped(t *testing.T) {
+	want := Errorf(codes.NotFound, "no agent %s", "x").WithDetail("agent", "x")
+	w := httptest.NewRecorder()
+	WriteError(w, fmt.Errorf("lookup: %w", want))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("got status %d, want %d", w.Code, http.StatusNotFound)
+	}
+	e, ok := statusErrorFromProblem(w.Body.Bytes())
+	if !ok || !reflect.DeepEqual(e, want) {
+		t.Errorf("problem %s decoded to %+v, want %+v", w.Body, e, want)
+	}
+}
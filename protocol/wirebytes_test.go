@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"strconv"
+	"testing"
+
+	pb "github.com/evaluate_serde_protocol/protocol/agent"
+	"google.golang.org/grpc"
+)
+
+type dialFunc func(network, address string) (net.Conn, error)
+
+// wireTransport starts a fresh server for one transport on listener and
+// returns a function performing call number n against it through dial, and a
+// function tearing both ends down.
+type wireTransport struct {
+	name  string
+	start func(listener net.Listener, dial dialFunc) (call func(n int), stop func())
+}
+
+// dialHTTPRPC performs the HTTP CONNECT handshake of rpc.DialHTTP on conn.
+func dialHTTPRPC(conn net.Conn) (*rpc.Client, error) {
+	io.WriteString(conn, "CONNECT "+rpc.DefaultRPCPath+" HTTP/1.0\n\n")
+	res, err := http.ReadResponse(bufio.NewReader(conn), &http.Request{Method: "CONNECT"})
+	if err != nil {
+		return nil, err
+	}
+	if res.Status != "200 Connected to Go RPC" {
+		return nil, errors.New("unexpected HTTP response: " + res.Status)
+	}
+	return rpc.NewClient(conn), nil
+}
+
+func newAgentRPCServer() *rpc.Server {
+	server := rpc.NewServer()
+	if err := server.Register(new(AgentHandler)); err != nil {
+		panic(err)
+	}
+	return server
+}
+
+// acceptLoop serves every accepted connection until listener is closed.
+// Unlike rpc.Server.Accept it does not log once the listener is closed.
+func acceptLoop(listener net.Listener, serve func(io.ReadWriteCloser)) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go serve(conn)
+	}
+}
+
+func rpcCaller(client *rpc.Client) func(n int) {
+	var reply AgentData
+	return func(n int) {
+		err := client.Call("AgentHandler.Serve", strconv.Itoa(n), &reply)
+		if err != nil {
+			panic(err)
+		}
+	}
+}
+
+func mustDial(dial dialFunc, listener net.Listener) net.Conn {
+	conn, err := dial("tcp", listener.Addr().String())
+	if err != nil {
+		panic(err)
+	}
+	return conn
+}
+
+func httpCaller(listener net.Listener, transport *http.Transport) (func(n int), func()) {
+	server := &http.Server{Handler: new(AgentHandler)}
+	go server.Serve(listener)
+
+	client := &http.Client{Transport: transport}
+	url := "http://" + listener.Addr().String() + "/"
+	return func(n int) {
+			sendRequest(client, url)
+		}, func() {
+			transport.CloseIdleConnections()
+			server.Close()
+		}
+}
+
+var wireTransports = []wireTransport{
+	{"TCPRPC", func(listener net.Listener, dial dialFunc) (func(int), func()) {
+		go acceptLoop(listener, newAgentRPCServer().ServeConn)
+		client := rpc.NewClient(mustDial(dial, listener))
+		return rpcCaller(client), func() {
+			client.Close()
+			listener.Close()
+		}
+	}},
+	{"JSONRPC", func(listener net.Listener, dial dialFunc) (func(int), func()) {
+		server := newAgentRPCServer()
+		go acceptLoop(listener, func(conn io.ReadWriteCloser) {
+			server.ServeCodec(jsonrpc.NewServerCodec(conn))
+		})
+		client := jsonrpc.NewClient(mustDial(dial, listener))
+		return rpcCaller(client), func() {
+			client.Close()
+			listener.Close()
+		}
+	}},
+	{"HTTPRPC", func(listener net.Listener, dial dialFunc) (func(int), func()) {
+		go http.Serve(listener, newAgentRPCServer())
+		client, err := dialHTTPRPC(mustDial(dial, listener))
+		if err != nil {
+			panic(err)
+		}
+		return rpcCaller(client), func() {
+			client.Close()
+			listener.Close()
+		}
+	}},
+	{"JSONRPC2", func(listener net.Listener, dial dialFunc) (func(int), func()) {
+		server := NewJSONRPCServer()
+		if err := server.Register(new(AgentHandler)); err != nil {
+			panic(err)
+		}
+		go server.Accept(listener)
+		client := NewJSONRPCClient(mustDial(dial, listener))
+		var reply AgentData
+		return func(n int) {
+				err := client.Call("AgentHandler.Serve", strconv.Itoa(n), &reply)
+				if err != nil {
+					panic(err)
+				}
+			}, func() {
+				client.Close()
+				listener.Close()
+			}
+	}},
+	{"GRPC", func(listener net.Listener, dial dialFunc) (func(int), func()) {
+		server := grpc.NewServer()
+		pb.RegisterAgentServer(server, new(AgentHandler))
+		go server.Serve(listener)
+
+		conn, err := grpc.Dial(listener.Addr().String(), grpc.WithInsecure(), grpc.WithContextDialer(func(ctx context.Context, address string) (net.Conn, error) {
+			return dial("tcp", address)
+		}))
+		if err != nil {
+			panic(err)
+		}
+		client := pb.NewAgentClient(conn)
+		return func(n int) {
+				_, err := client.ServeAgentProto(context.Background(), &pb.AgentRequest{Data: strconv.Itoa(n)})
+				if err != nil {
+					panic(err)
+				}
+			}, func() {
+				conn.Close()
+				server.Stop()
+			}
+	}},
+	{"HTTP", func(listener net.Listener, dial dialFunc) (func(int), func()) {
+		return httpCaller(listener, &http.Transport{Dial: dial})
+	}},
+	{"HTTPNoKeepAlive", func(listener net.Listener, dial dialFunc) (func(int), func()) {
+		return httpCaller(listener, &http.Transport{Dial: dial, DisableKeepAlives: true})
+	}},
+}
+
+func TestByteCounter(t *testing.T) {
+	for _, transport := range wireTransports {
+		server, client := new(ByteCounter), new(ByteCounter)
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		call, stop := transport.start(server.Listener(listener), client.Dial)
+		call(0)
+		call(1)
+
+		if client.Written() == 0 || client.Read() == 0 {
+			t.Errorf("%s: client counted %d bytes sent, %d received", transport.name, client.Written(), client.Read())
+		}
+		// A completed call implies the server has read the whole request
+		// and the client the whole response, but HTTP/2 control frames
+		// may still be in flight in either direction.
+		if transport.name != "GRPC" && (server.Read() != client.Written() || server.Written() != client.Read()) {
+			t.Errorf("%s: server read %d/wrote %d, client wrote %d/read %d", transport.name,
+				server.Read(), server.Written(), client.Written(), client.Read())
+		}
+		stop()
+	}
+}
+
+// BenchmarkWireBytes reports the bytes each transport puts on the wire per
+// call, as seen by the client: sent-B/op and recv-B/op for steady-state calls
+// and first-call-B for the first call on a fresh client, which also carries
+// the connection handshake and one-off metadata such as gob type descriptors.
+func BenchmarkWireBytes(b *testing.B) {
+	for _, transport := range wireTransports {
+		b.Run(transport.name, func(b *testing.B) {
+			server, client := new(ByteCounter), new(ByteCounter)
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				panic(err)
+			}
+			call, stop := transport.start(server.Listener(listener), client.Dial)
+			defer stop()
+
+			call(0)
+			first := client.Written() + client.Read()
+			client.Reset()
+
+			b.ResetTimer()
+			for n := 0; n < b.N; n++ {
+				call(n)
+			}
+			b.StopTimer()
+
+			b.ReportMetric(float64(client.Written())/float64(b.N), "sent-B/op")
+			b.ReportMetric(float64(client.Read())/float64(b.N), "recv-B/op")
+			b.ReportMetric(float64(first), "first-call-B")
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// scanProto calls fn with every field of the protobuf message in b, in wire
+// order, until fn returns false. v is the field's encoded value: the varint
+// or fixed-size bytes, or the contents of a length-delimited field. It
+// aliases b, so scanning allocates nothing.
+func scanProto(b []byte, fn func(num protowire.Number, typ protowire.Type, v []byte) bool) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		var v []byte
+		if typ == protowire.BytesType {
+			v, n = protowire.ConsumeBytes(b)
+		} else {
+			n = protowire.ConsumeFieldValue(num, typ, b)
+			if n >= 0 {
+				v = b[:n]
+			}
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		if !fn(num, typ, v) {
+			return nil
+		}
+		b = b[n:]
+	}
+	return nil
+}
+
+// extractProtoFields sets values[i] to the encoded value of field nums[i]
+// in the message b, as scanProto reports it, or to nil if the field is
+// absent. As when unmarshalling a scalar, the last occurrence of a field
+// wins; repeated fields should be read with scanProto instead.
+func extractProtoFields(b []byte, nums []protowire.Number, values [][]byte) error {
+	for i := range values {
+		values[i] = nil
+	}
+	return scanProto(b, func(num protowire.Number, typ protowire.Type, v []byte) bool {
+		for i, want := range nums {
+			if num == want {
+				values[i] = v
+			}
+		}
+		return true
+	})
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+// protoBufPresets are the sizes of the messages the partial decoding tests
+// and benchmarks run on. They are named after the payload presets of the
+// protocol benchmarks and built with repeatProtoBufObject.
+var protoBufPresets = []struct {
+	name   string
+	repeat int
+}{
+	{"small", 1},
+	{"medium", 50},
+	{"large", 5000},
+}
+
+// repeatProtoBufObject returns generateProtoBufObject with its status and
+// lsns repeated n times.
+func repeatProtoBufObject(n int) *AgentProto {
+	obj := generateProtoBufObject()
+	obj.Status = strings.Repeat(obj.Status, n)
+	lsns := make([]string, 0, n*len(obj.Lsns))
+	for i := 0; i < n; i++ {
+		lsns = append(lsns, obj.Lsns...)
+	}
+	obj.Lsns = lsns
+	return obj
+}
+
+// agentHeader decodes only the hostname and timestamp of an AgentProto,
+// skipping the status and lsns without allocating them.
+func agentHeader(b []byte) (hostname string, timestamp int64, err error) {
+	var host []byte
+	err = scanProto(b, func(num protowire.Number, typ protowire.Type, v []byte) bool {
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			host = v
+		case num == 3 && typ == protowire.VarintType:
+			x, _ := protowire.ConsumeVarint(v)
+			timestamp = int64(x)
+		}
+		return true
+	})
+	return string(host), timestamp, err
+}
+
+func TestAgentHeader(t *testing.T) {
+	for _, preset := range protoBufPresets {
+		want := repeatProtoBufObject(preset.repeat)
+		out, err := proto.Marshal(want)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hostname, timestamp, err := agentHeader(out)
+		if err != nil {
+			t.Fatalf("%s: %v", preset.name, err)
+		}
+		if hostname != want.Hostname || timestamp != want.Timestamp {
+			t.Errorf("%s: got %q, %d, want %q, %d", preset.name, hostname, timestamp, want.Hostname, want.Timestamp)
+		}
+		if _, _, err := agentHeader(out[:len(out)-1]); err == nil {
+			t.Errorf("%s: truncated message scanned without error", preset.name)
+		}
+	}
+}
+
+func TestExtractProtoFields(t *testing.T) {
+	out, err := proto.Marshal(generateProtoBufObject())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A later occurrence of a field overrides the earlier one, as with
+	// proto.Merge.
+	out = protowire.AppendTag(out, 1, protowire.BytesType)
+	out = protowire.AppendString(out, "10.64.6.139")
+
+	values := make([][]byte, 3)
+	if err := extractProtoFields(out, []protowire.Number{1, 3, 5}, values); err != nil {
+		t.Fatal(err)
+	}
+	var want AgentProto
+	if err := proto.Unmarshal(out, &want); err != nil {
+		t.Fatal(err)
+	}
+	if string(values[0]) != want.Hostname {
+		t.Errorf("hostname %q, want %q", values[0], want.Hostname)
+	}
+	if x, _ := protowire.ConsumeVarint(values[1]); int64(x) != want.Timestamp {
+		t.Errorf("timestamp %d, want %d", x, want.Timestamp)
+	}
+	if values[2] != nil {
+		t.Errorf("absent field 5 = %x", values[2])
+	}
+}
+
+// BenchmarkProtoBufPartialDecode compares reading the hostname and
+// timestamp of an AgentProto by scanning the wire bytes against fully
+// unmarshalling it.
+func BenchmarkProtoBufPartialDecode(b *testing.B) {
+	for _, preset := range protoBufPresets {
+		want := repeatProtoBufObject(preset.repeat)
+		out, err := proto.Marshal(want)
+		if err != nil {
+			panic(err)
+		}
+
+		b.Run(preset.name+"/scan", func(b *testing.B) {
+			b.SetBytes(int64(len(out)))
+			for n := 0; n < b.N; n++ {
+				hostname, _, err := agentHeader(out)
+				if err != nil || hostname != want.Hostname {
+					panic(err)
+				}
+			}
+		})
+		b.Run(preset.name+"/unmarshal", func(b *testing.B) {
+			b.SetBytes(int64(len(out)))
+			obj := &AgentProto{}
+			for n := 0; n < b.N; n++ {
+				err := proto.Unmarshal(out, obj)
+				if err != nil || obj.Hostname != want.Hostname {
+					panic(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,474 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// Thrift type ids, as written by TBinaryProtocol.
+const (
+	thriftStop   byte = 0
+	thriftBool   byte = 2
+	thriftByte   byte = 3
+	thriftDouble byte = 4
+	thriftI16    byte = 6
+	thriftI32    byte = 8
+	thriftI64    byte = 10
+	thriftString byte = 11
+	thriftStruct byte = 12
+	thriftMap    byte = 13
+	thriftSet    byte = 14
+	thriftList   byte = 15
+)
+
+var errThriftCorrupt = errors.New("thrift: corrupt data")
+
+// thriftWriter is the subset of Thrift's TProtocol needed to write structs
+// of strings, integers and lists, appending to a byte slice.
+type thriftWriter interface {
+	writeStructBegin()
+	writeStructEnd()
+	writeFieldBegin(typ byte, id int16)
+	writeFieldStop()
+	writeListBegin(elemType byte, size int)
+	writeI64(v int64)
+	writeString(s string)
+	// bytes returns the encoded data and resets the writer to append to
+	// buf.
+	bytes(buf []byte) []byte
+}
+
+// thriftReader is the reading counterpart of thriftWriter. The first error
+// is sticky and reported by err.
+type thriftReader interface {
+	readStructBegin()
+	readStructEnd()
+	// readFieldBegin returns thriftStop after the last field.
+	readFieldBegin() (typ byte, id int16)
+	readListBegin() (elemType byte, size int)
+	readI64() int64
+	readString() string
+	skip(typ byte)
+	err() error
+}
+
+// thriftBinaryWriter implements TBinaryProtocol: fixed-width big-endian
+// integers and 32-bit length prefixes.
+type thriftBinaryWriter struct {
+	buf []byte
+}
+
+func (w *thriftBinaryWriter) writeStructBegin() {}
+
+func (w *thriftBinaryWriter) writeStructEnd() {}
+
+func (w *thriftBinaryWriter) writeFieldBegin(typ byte, id int16) {
+	w.buf = append(w.buf, typ, byte(id>>8), byte(id))
+}
+
+func (w *thriftBinaryWriter) writeFieldStop() {
+	w.buf = append(w.buf, thriftStop)
+}
+
+func (w *thriftBinaryWriter) writeListBegin(elemType byte, size int) {
+	w.buf = append(w.buf, elemType)
+	w.buf = appendUint32(w.buf, uint32(size))
+}
+
+func (w *thriftBinaryWriter) writeI64(v int64) {
+	w.buf = append(w.buf, 0, 0, 0, 0, 0, 0, 0, 0)
+	binary.BigEndian.PutUint64(w.buf[len(w.buf)-8:], uint64(v))
+}
+
+func (w *thriftBinaryWriter) writeString(s string) {
+	w.buf = appendUint32(w.buf, uint32(len(s)))
+	w.buf = append(w.buf, s...)
+}
+
+func (w *thriftBinaryWriter) bytes(buf []byte) []byte {
+	out := w.buf
+	w.buf = buf
+	return out
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	return append(b, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+type thriftBinaryReader struct {
+	buf []byte
+	e   error
+}
+
+func (r *thriftBinaryReader) readStructBegin() {}
+
+func (r *thriftBinaryReader) readStructEnd() {}
+
+func (r *thriftBinaryReader) next(n int) []byte {
+	if n < 0 || n > len(r.buf) {
+		r.fail()
+		return nil
+	}
+	b := r.buf[:n]
+	r.buf = r.buf[n:]
+	return b
+}
+
+func (r *thriftBinaryReader) readFieldBegin() (byte, int16) {
+	b := r.next(1)
+	if b == nil || b[0] == thriftStop {
+		return thriftStop, 0
+	}
+	id := r.next(2)
+	if id == nil {
+		return thriftStop, 0
+	}
+	return b[0], int16(binary.BigEndian.Uint16(id))
+}
+
+func (r *thriftBinaryReader) readListBegin() (byte, int) {
+	b := r.next(5)
+	if b == nil {
+		return thriftStop, 0
+	}
+	size := int32(binary.BigEndian.Uint32(b[1:]))
+	// Every element takes at least one byte.
+	if size < 0 || int(size) > len(r.buf) {
+		r.fail()
+		return thriftStop, 0
+	}
+	return b[0], int(size)
+}
+
+func (r *thriftBinaryReader) readI64() int64 {
+	b := r.next(8)
+	if b == nil {
+		return 0
+	}
+	return int64(binary.BigEndian.Uint64(b))
+}
+
+func (r *thriftBinaryReader) readString() string {
+	b := r.next(4)
+	if b == nil {
+		return ""
+	}
+	return string(r.next(int(int32(binary.BigEndian.Uint32(b)))))
+}
+
+func (r *thriftBinaryReader) skip(typ byte) {
+	switch typ {
+	case thriftBool, thriftByte:
+		r.next(1)
+	case thriftI16:
+		r.next(2)
+	case thriftI32:
+		r.next(4)
+	case thriftI64, thriftDouble:
+		r.next(8)
+	case thriftString:
+		r.readString()
+	case thriftStruct:
+		skipThriftStruct(r)
+	case thriftMap:
+		b := r.next(6)
+		if b == nil {
+			return
+		}
+		size := int32(binary.BigEndian.Uint32(b[2:]))
+		if size < 0 || int(size) > len(r.buf) {
+			r.fail()
+			return
+		}
+		for i := 0; i < int(size) && r.e == nil; i++ {
+			r.skip(b[0])
+			r.skip(b[1])
+		}
+	case thriftSet, thriftList:
+		elemType, size := r.readListBegin()
+		for i := 0; i < size && r.e == nil; i++ {
+			r.skip(elemType)
+		}
+	default:
+		r.fail()
+	}
+}
+
+func (r *thriftBinaryReader) fail() {
+	if r.e == nil {
+		r.e = errThriftCorrupt
+	}
+	r.buf = nil
+}
+
+func (r *thriftBinaryReader) err() error {
+	return r.e
+}
+
+func skipThriftStruct(r thriftReader) {
+	r.readStructBegin()
+	for r.err() == nil {
+		typ, _ := r.readFieldBegin()
+		if typ == thriftStop {
+			break
+		}
+		r.skip(typ)
+	}
+	r.readStructEnd()
+}
+
+// Thrift compact protocol type ids, which differ from the binary ones.
+const (
+	compactBoolTrue  byte = 1
+	compactBoolFalse byte = 2
+	compactByte      byte = 3
+	compactI16       byte = 4
+	compactI32       byte = 5
+	compactI64       byte = 6
+	compactDouble    byte = 7
+	compactBinary    byte = 8
+	compactList      byte = 9
+	compactSet       byte = 10
+	compactMap       byte = 11
+	compactStruct    byte = 12
+)
+
+var (
+	thriftToCompact = [...]byte{
+		thriftBool: compactBoolTrue, thriftByte: compactByte, thriftDouble: compactDouble,
+		thriftI16: compactI16, thriftI32: compactI32, thriftI64: compactI64,
+		thriftString: compactBinary, thriftStruct: compactStruct, thriftMap: compactMap,
+		thriftSet: compactSet, thriftList: compactList,
+	}
+	compactToThrift = [...]byte{
+		compactBoolTrue: thriftBool, compactBoolFalse: thriftBool, compactByte: thriftByte,
+		compactI16: thriftI16, compactI32: thriftI32, compactI64: thriftI64,
+		compactDouble: thriftDouble, compactBinary: thriftString, compactList: thriftList,
+		compactSet: thriftSet, compactMap: thriftMap, compactStruct: thriftStruct,
+	}
+)
+
+// thriftCompactWriter implements TCompactProtocol: zig-zag varints, field
+// ids written as a delta from the previous field where it fits in a nibble,
+// and list sizes below 15 packed with the element type.
+type thriftCompactWriter struct {
+	buf       []byte
+	lastField []int16
+	lastID    int16
+}
+
+func (w *thriftCompactWriter) writeStructBegin() {
+	w.lastField = append(w.lastField, w.lastID)
+	w.lastID = 0
+}
+
+func (w *thriftCompactWriter) writeStructEnd() {
+	w.lastID = w.lastField[len(w.lastField)-1]
+	w.lastField = w.lastField[:len(w.lastField)-1]
+}
+
+func (w *thriftCompactWriter) writeFieldBegin(typ byte, id int16) {
+	ctype := thriftToCompact[typ]
+	if delta := id - w.lastID; delta > 0 && delta <= 15 {
+		w.buf = append(w.buf, byte(delta)<<4|ctype)
+	} else {
+		w.buf = append(w.buf, ctype)
+		w.buf = appendUvarint(w.buf, zigzag64(int64(id)))
+	}
+	w.lastID = id
+}
+
+func (w *thriftCompactWriter) writeFieldStop() {
+	w.buf = append(w.buf, thriftStop)
+}
+
+func (w *thriftCompactWriter) writeListBegin(elemType byte, size int) {
+	ctype := thriftToCompact[elemType]
+	if size < 15 {
+		w.buf = append(w.buf, byte(size)<<4|ctype)
+		return
+	}
+	w.buf = append(w.buf, 0xf0|ctype)
+	w.buf = appendUvarint(w.buf, uint64(size))
+}
+
+func (w *thriftCompactWriter) writeI64(v int64) {
+	w.buf = appendUvarint(w.buf, zigzag64(v))
+}
+
+func (w *thriftCompactWriter) writeString(s string) {
+	w.buf = appendUvarint(w.buf, uint64(len(s)))
+	w.buf = append(w.buf, s...)
+}
+
+func (w *thriftCompactWriter) bytes(buf []byte) []byte {
+	out := w.buf
+	w.buf = buf
+	w.lastField = w.lastField[:0]
+	w.lastID = 0
+	return out
+}
+
+func appendUvarint(b []byte, v uint64) []byte {
+	for v >= 0x80 {
+		b = append(b, byte(v)|0x80)
+		v >>= 7
+	}
+	return append(b, byte(v))
+}
+
+func zigzag64(v int64) uint64 {
+	return uint64(v<<1) ^ uint64(v>>63)
+}
+
+type thriftCompactReader struct {
+	buf       []byte
+	e         error
+	lastField []int16
+	lastID    int16
+	// boolValue holds the value of a bool field, which the compact
+	// protocol packs into the field header.
+	boolValue byte
+}
+
+func (r *thriftCompactReader) readStructBegin() {
+	r.lastField = append(r.lastField, r.lastID)
+	r.lastID = 0
+}
+
+func (r *thriftCompactReader) readStructEnd() {
+	if len(r.lastField) == 0 {
+		return
+	}
+	r.lastID = r.lastField[len(r.lastField)-1]
+	r.lastField = r.lastField[:len(r.lastField)-1]
+}
+
+func (r *thriftCompactReader) readByte() byte {
+	if len(r.buf) == 0 {
+		r.fail()
+		return 0
+	}
+	b := r.buf[0]
+	r.buf = r.buf[1:]
+	return b
+}
+
+func (r *thriftCompactReader) readUvarint() uint64 {
+	v, n := binary.Uvarint(r.buf)
+	if n <= 0 {
+		r.fail()
+		return 0
+	}
+	r.buf = r.buf[n:]
+	return v
+}
+
+func (r *thriftCompactReader) readFieldBegin() (byte, int16) {
+	b := r.readByte()
+	if b == thriftStop || r.e != nil {
+		return thriftStop, 0
+	}
+	ctype := b & 0x0f
+	if int(ctype) >= len(compactToThrift) || compactToThrift[ctype] == thriftStop {
+		r.fail()
+		return thriftStop, 0
+	}
+	if delta := int16(b >> 4); delta != 0 {
+		r.lastID += delta
+	} else {
+		v := r.readUvarint()
+		r.lastID = int16(int64(v>>1) ^ -int64(v&1))
+	}
+	r.boolValue = ctype
+	return compactToThrift[ctype], r.lastID
+}
+
+func (r *thriftCompactReader) readListBegin() (byte, int) {
+	b := r.readByte()
+	size := uint64(b >> 4)
+	if size == 15 {
+		size = r.readUvarint()
+	}
+	ctype := b & 0x0f
+	if r.e != nil || int(ctype) >= len(compactToThrift) || size > uint64(len(r.buf)) {
+		r.fail()
+		return thriftStop, 0
+	}
+	return compactToThrift[ctype], int(size)
+}
+
+func (r *thriftCompactReader) readI64() int64 {
+	v := r.readUvarint()
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func (r *thriftCompactReader) readString() string {
+	n := r.readUvarint()
+	if n > uint64(len(r.buf)) {
+		r.fail()
+		return ""
+	}
+	s := string(r.buf[:n])
+	r.buf = r.buf[n:]
+	return s
+}
+
+func (r *thriftCompactReader) skip(typ byte) {
+	switch typ {
+	case thriftBool:
+		// Bool fields carry their value in the field header; only list
+		// elements take a byte.
+		if r.boolValue != compactBoolTrue && r.boolValue != compactBoolFalse {
+			r.readByte()
+		}
+	case thriftByte:
+		r.readByte()
+	case thriftI16, thriftI32, thriftI64:
+		r.readUvarint()
+	case thriftDouble:
+		if len(r.buf) < 8 {
+			r.fail()
+			return
+		}
+		r.buf = r.buf[8:]
+	case thriftString:
+		r.readString()
+	case thriftStruct:
+		skipThriftStruct(r)
+	case thriftMap:
+		size := r.readUvarint()
+		if size == 0 {
+			return
+		}
+		types := r.readByte()
+		if r.e != nil || size > uint64(len(r.buf)) || int(types>>4) >= len(compactToThrift) || int(types&0x0f) >= len(compactToThrift) {
+			r.fail()
+			return
+		}
+		r.boolValue = 0
+		for i := uint64(0); i < size && r.e == nil; i++ {
+			r.skip(compactToThrift[types>>4])
+			r.skip(compactToThrift[types&0x0f])
+		}
+	case thriftSet, thriftList:
+		elemType, size := r.readListBegin()
+		r.boolValue = 0
+		for i := 0; i < size && r.e == nil; i++ {
+			r.skip(elemType)
+		}
+	default:
+		r.fail()
+	}
+}
+
+func (r *thriftCompactReader) fail() {
+	if r.e == nil {
+		r.e = errThriftCorrupt
+	}
+	r.buf = nil
+}
+
+func (r *thriftCompactReader) err() error {
+	return r.e
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+// writeThrift writes a as the Thrift struct
+//
+//	struct AgentData {
+//	  1: string hostname
+//	  2: string status
+//	  3: i64 timestamp
+//	  4: list<string> lsns
+//	}
+//
+// which has the field ids of AgentProto.
+func (a *AgentData) writeThrift(w thriftWriter) {
+	w.writeStructBegin()
+	w.writeFieldBegin(thriftString, 1)
+	w.writeString(a.Hostname)
+	w.writeFieldBegin(thriftString, 2)
+	w.writeString(a.Status)
+	w.writeFieldBegin(thriftI64, 3)
+	w.writeI64(int64(a.Timestamp))
+	w.writeFieldBegin(thriftList, 4)
+	w.writeListBegin(thriftString, len(a.Lsns))
+	for _, lsn := range a.Lsns {
+		w.writeString(lsn)
+	}
+	w.writeFieldStop()
+	w.writeStructEnd()
+}
+
+// readThrift decodes a struct written by writeThrift into a, skipping
+// unknown fields.
+func (a *AgentData) readThrift(r thriftReader) error {
+	*a = AgentData{Lsns: a.Lsns[:0]}
+	r.readStructBegin()
+	for r.err() == nil {
+		typ, id := r.readFieldBegin()
+		if typ == thriftStop {
+			break
+		}
+		switch {
+		case id == 1 && typ == thriftString:
+			a.Hostname = r.readString()
+		case id == 2 && typ == thriftString:
+			a.Status = r.readString()
+		case id == 3 && typ == thriftI64:
+			a.Timestamp = int(r.readI64())
+		case id == 4 && typ == thriftList:
+			elemType, size := r.readListBegin()
+			if elemType != thriftString {
+				for i := 0; i < size; i++ {
+					r.skip(elemType)
+				}
+				continue
+			}
+			for i := 0; i < size; i++ {
+				a.Lsns = append(a.Lsns, r.readString())
+			}
+		default:
+			r.skip(typ)
+		}
+	}
+	r.readStructEnd()
+	return r.err()
+}
+
+func TestThriftRoundTrip(t *testing.T) {
+	want := generateObject()
+	for _, codec := range []struct {
+		name string
+		w    thriftWriter
+		r    func([]byte) thriftReader
+	}{
+		{"binary", &thriftBinaryWriter{}, func(b []byte) thriftReader { return &thriftBinaryReader{buf: b} }},
+		{"compact", &thriftCompactWriter{}, func(b []byte) thriftReader { return &thriftCompactReader{buf: b} }},
+	} {
+		want.writeThrift(codec.w)
+		out := codec.w.bytes(nil)
+		got := &AgentData{}
+		if err := got.readThrift(codec.r(out)); err != nil {
+			t.Fatalf("%s: %v", codec.name, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %+v, want %+v", codec.name, got, want)
+		}
+		if err := got.readThrift(codec.r(out[:len(out)-1])); err == nil {
+			t.Errorf("%s: truncated struct decoded without error", codec.name)
+		}
+	}
+}
+
+func TestThriftCompactEncoding(t *testing.T) {
+	w := &thriftCompactWriter{}
+	(&AgentData{Hostname: "h", Timestamp: -1, Lsns: []string{"x"}}).writeThrift(w)
+	want := []byte{
+		0x18, 1, 'h', // field 1, delta 1, binary
+		0x18, 0, // field 2, delta 1, binary
+		0x16, 1, // field 3, delta 1, i64 -1 zig-zagged
+		0x19, 0x18, 1, 'x', // field 4, delta 1, list of one binary
+		0, // stop
+	}
+	if out := w.bytes(nil); !bytes.Equal(out, want) {
+		t.Errorf("got % x, want % x", out, want)
+	}
+}
+
+// TestThriftSkip decodes a newer version of the struct whose unknown fields
+// must be skipped.
+func TestThriftSkip(t *testing.T) {
+	for _, w := range []thriftWriter{&thriftBinaryWriter{}, &thriftCompactWriter{}} {
+		w.writeStructBegin()
+		w.writeFieldBegin(thriftString, 1)
+		w.writeString("db1")
+		w.writeFieldBegin(thriftStruct, 20)
+		w.writeStructBegin()
+		w.writeFieldBegin(thriftList, 1)
+		w.writeListBegin(thriftI64, 20)
+		for i := 0; i < 20; i++ {
+			w.writeI64(int64(i))
+		}
+		w.writeFieldStop()
+		w.writeStructEnd()
+		w.writeFieldBegin(thriftI64, 3)
+		w.writeI64(42)
+		w.writeFieldStop()
+		w.writeStructEnd()
+		out := w.bytes(nil)
+
+		var r thriftReader = &thriftBinaryReader{buf: out}
+		if _, ok := w.(*thriftCompactWriter); ok {
+			r = &thriftCompactReader{buf: out}
+		}
+		got := &AgentData{}
+		if err := got.readThrift(r); err != nil {
+			t.Fatalf("%T: %v", w, err)
+		}
+		if got.Hostname != "db1" || got.Timestamp != 42 {
+			t.Errorf("%T: got %+v", w, got)
+		}
+	}
+}
+
+func benchmarkThriftMarshal(b *testing.B, w thriftWriter) {
+	obj := generateObject()
+	var out []byte
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		obj.writeThrift(w)
+		out = w.bytes(out[:0])
+	}
+	b.ReportMetric(float64(len(out)), "bytes")
+}
+
+func benchmarkThriftUnmarshal(b *testing.B, w thriftWriter, newReader func([]byte) thriftReader) {
+	generateObject().writeThrift(w)
+	out := w.bytes(nil)
+
+	obj := &AgentData{}
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		err := obj.readThrift(newReader(out))
+		if err != nil {
+			panic(err)
+		}
+	}
+}
+
+func BenchmarkThriftBinaryMarshal(b *testing.B) {
+	benchmarkThriftMarshal(b, &thriftBinaryWriter{})
+}
+
+func BenchmarkThriftBinaryUnmarshal(b *testing.B) {
+	benchmarkThriftUnmarshal(b, &thriftBinaryWriter{}, func(out []byte) thriftReader {
+		return &thriftBinaryReader{buf: out}
+	})
+}
+
+func BenchmarkThriftCompactMarshal(b *testing.B) {
+	benchmarkThriftMarshal(b, &thriftCompactWriter{})
+}
+
+func BenchmarkThriftCompactUnmarshal(b *testing.B) {
+	benchmarkThriftUnmarshal(b, &thriftCompactWriter{}, func(out []byte) thriftReader {
+		return &thriftCompactReader{buf: out}
+	})
+}
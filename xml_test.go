@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/xml"
+	"reflect"
+	"testing"
+)
+
+func TestXMLRoundTrip(t *testing.T) {
+	want := generateObject()
+	out, err := xml.Marshal(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const doc = `<AgentData><hostname>10.64.6.138</hostname><status>In Progress</status>` +
+		`<timestamp>1282368345</timestamp><lsns><lsn>16/B374D848</lsn><lsn>16/B374D010</lsn></lsns></AgentData>`
+	if string(out) != doc {
+		t.Errorf("got %s, want %s", out, doc)
+	}
+	got := &AgentData{}
+	if err := xml.Unmarshal(out, got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func BenchmarkXMLMarshal(b *testing.B) {
+	obj := generateObject()
+	var out []byte
+	var err error
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		out, err = xml.Marshal(obj)
+		if err != nil {
+			panic(err)
+		}
+	}
+	b.ReportMetric(float64(len(out)), "bytes")
+}
+
+func BenchmarkXMLUnmarshal(b *testing.B) {
+	out, err := xml.Marshal(generateObject())
+	if err != nil {
+		panic(err)
+	}
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		// xml.Unmarshal appends to slices, so each message needs a fresh
+		// AgentData.
+		err = xml.Unmarshal(out, &AgentData{})
+		if err != nil {
+			panic(err)
+		}
+	}
+}
@@ -0,0 +1,440 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// This file holds a YAML codec for the block-style subset that describes
+// AgentData: a mapping at the top level whose values are scalars or block
+// sequences of scalars. Plain, single-quoted and double-quoted scalars,
+// comments and a leading document marker are accepted; flow collections
+// other than [], nested mappings, anchors, tags and block scalars are not.
+
+// yamlWords are plain scalars that YAML resolves to something other than a
+// string, in some version of the specification.
+var yamlWords = map[string]bool{
+	"": true, "~": true, "null": true, "true": true, "false": true,
+	"yes": true, "no": true, "on": true, "off": true, "y": true, "n": true,
+	".inf": true, "-.inf": true, "+.inf": true, ".nan": true,
+}
+
+// yamlPlain reports whether s can be written as a plain scalar and read
+// back as the same string.
+func yamlPlain(s string) bool {
+	if len(s) <= 5 && yamlWords[strings.ToLower(s)] || !utf8.ValidString(s) {
+		return false
+	}
+	if strings.IndexByte("-?:,[]{}#&*!|>'\"%@` \t", s[0]) >= 0 {
+		return false
+	}
+	if last := s[len(s)-1]; last == ' ' || last == '\t' || last == ':' {
+		return false
+	}
+	if strings.Contains(s, ": ") || strings.Contains(s, " #") {
+		return false
+	}
+	for _, r := range s {
+		if r != ' ' && !unicode.IsPrint(r) {
+			return false
+		}
+	}
+	return !yamlNumber(s)
+}
+
+// yamlNumber reports whether s reads as an integer or float in YAML 1.1 or
+// 1.2: decimal with optional underscores, sexagesimal colons, fraction and
+// exponent, or 0x, 0o and 0b prefixed. The special floats are in yamlWords.
+func yamlNumber(s string) bool {
+	if s[0] == '+' || s[0] == '-' {
+		s = s[1:]
+	}
+	if len(s) > 2 && s[0] == '0' && strings.IndexByte("xob", s[1]) >= 0 {
+		digits := "01_"
+		switch s[1] {
+		case 'x':
+			digits = "0123456789abcdefABCDEF_"
+		case 'o':
+			digits = "01234567_"
+		}
+		for i := 2; i < len(s); i++ {
+			if strings.IndexByte(digits, s[i]) < 0 {
+				return false
+			}
+		}
+		return true
+	}
+	digits, dot, i := 0, false, 0
+	for ; i < len(s); i++ {
+		switch c := s[i]; {
+		case '0' <= c && c <= '9':
+			digits++
+		case c == '_' || c == ':' && !dot:
+		case c == '.' && !dot:
+			dot = true
+		default:
+			goto exponent
+		}
+	}
+exponent:
+	if digits == 0 {
+		return false
+	}
+	if i == len(s) {
+		return true
+	}
+	if s[i] != 'e' && s[i] != 'E' {
+		return false
+	}
+	i++
+	if i < len(s) && (s[i] == '+' || s[i] == '-') {
+		i++
+	}
+	if i == len(s) {
+		return false
+	}
+	for ; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// appendYAMLString appends s as a scalar, plain if possible and otherwise
+// double-quoted. Invalid UTF-8 is written as U+FFFD.
+func appendYAMLString(b []byte, s string) []byte {
+	if yamlPlain(s) {
+		return append(b, s...)
+	}
+	b = append(b, '"')
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			b = append(b, '\\', byte(r))
+		case r == '\n':
+			b = append(b, '\\', 'n')
+		case r == '\t':
+			b = append(b, '\\', 't')
+		case r == '\r':
+			b = append(b, '\\', 'r')
+		case r < 0x80 && !unicode.IsPrint(r):
+			b = append(b, fmt.Sprintf(`\x%02x`, r)...)
+		case r > 0xffff && !unicode.IsPrint(r):
+			b = append(b, fmt.Sprintf(`\U%08x`, r)...)
+		case !unicode.IsPrint(r):
+			b = append(b, fmt.Sprintf(`\u%04x`, r)...)
+		default:
+			b = appendRune(b, r)
+		}
+	}
+	return append(b, '"')
+}
+
+func appendRune(b []byte, r rune) []byte {
+	var buf [utf8.UTFMax]byte
+	n := utf8.EncodeRune(buf[:], r)
+	return append(b, buf[:n]...)
+}
+
+// appendYAMLStringField appends the mapping entry key: s.
+func appendYAMLStringField(b []byte, key, s string) []byte {
+	b = append(b, key...)
+	b = append(b, ':', ' ')
+	b = appendYAMLString(b, s)
+	return append(b, '\n')
+}
+
+// appendYAMLIntField appends the mapping entry key: v.
+func appendYAMLIntField(b []byte, key string, v int64) []byte {
+	b = append(b, key...)
+	b = append(b, ':', ' ')
+	b = strconv.AppendInt(b, v, 10)
+	return append(b, '\n')
+}
+
+// appendYAMLStringSeqField appends key followed by ss as a block sequence,
+// or as [] if ss is empty.
+func appendYAMLStringSeqField(b []byte, key string, ss []string) []byte {
+	b = append(b, key...)
+	if len(ss) == 0 {
+		return append(b, ':', ' ', '[', ']', '\n')
+	}
+	b = append(b, ':', '\n')
+	for _, s := range ss {
+		b = append(b, ' ', ' ', '-', ' ')
+		b = appendYAMLString(b, s)
+		b = append(b, '\n')
+	}
+	return b
+}
+
+// yamlReader reads the entries of a top-level mapping. next returns each
+// key; one of the value methods, or skip, must be called before the next
+// key. The first error is sticky and reported by err.
+type yamlReader struct {
+	lines [][]byte
+	line  int    // index of the next line
+	value []byte // the rest of the current key's line
+	e     error
+}
+
+func newYAMLReader(data []byte) *yamlReader {
+	r := &yamlReader{lines: bytes.Split(data, []byte("\n"))}
+	r.skipBlank()
+	if r.line < len(r.lines) && string(bytes.TrimRight(r.lines[r.line], " \r")) == "---" {
+		r.line++
+	}
+	return r
+}
+
+func (r *yamlReader) fail(format string, args ...interface{}) {
+	if r.e == nil {
+		r.e = fmt.Errorf("yaml: line %d: %s", r.line, fmt.Sprintf(format, args...))
+	}
+	r.line = len(r.lines)
+}
+
+func (r *yamlReader) err() error {
+	return r.e
+}
+
+// skipBlank skips empty and comment-only lines.
+func (r *yamlReader) skipBlank() {
+	for r.line < len(r.lines) {
+		l := bytes.TrimLeft(r.lines[r.line], " ")
+		if len(bytes.TrimSpace(l)) != 0 && l[0] != '#' {
+			return
+		}
+		r.line++
+	}
+}
+
+// next returns the next key, or false at the end of the document.
+func (r *yamlReader) next() (string, bool) {
+	r.skipBlank()
+	if r.e != nil || r.line >= len(r.lines) {
+		return "", false
+	}
+	l := bytes.TrimRight(r.lines[r.line], " \r")
+	r.line++
+	if l[0] == ' ' || l[0] == '\t' {
+		r.fail("unexpected indentation")
+		return "", false
+	}
+	i := bytes.IndexByte(l, ':')
+	for i >= 0 && i+1 < len(l) && l[i+1] != ' ' {
+		j := bytes.IndexByte(l[i+1:], ':')
+		if j < 0 {
+			i = -1
+			break
+		}
+		i += 1 + j
+	}
+	if i <= 0 {
+		r.fail("expected a mapping key")
+		return "", false
+	}
+	key, err := yamlScalar(l[:i])
+	if err != nil {
+		r.fail("%v", err)
+		return "", false
+	}
+	r.value = bytes.TrimLeft(l[i+1:], " ")
+	return key, true
+}
+
+// yamlScalar decodes a plain or quoted scalar, ignoring a trailing comment.
+func yamlScalar(v []byte) (string, error) {
+	if len(v) == 0 {
+		return "", nil
+	}
+	switch v[0] {
+	case '\'':
+		return yamlSingleQuoted(v)
+	case '"':
+		return yamlDoubleQuoted(v)
+	case '[', '{', '&', '*', '!', '|', '>', '@', '`':
+		return "", fmt.Errorf("unsupported scalar %q", v)
+	}
+	if i := bytes.Index(v, []byte(" #")); i >= 0 {
+		v = v[:i]
+	}
+	s := string(bytes.TrimRight(v, " \t"))
+	switch s {
+	case "~", "null", "Null", "NULL":
+		return "", nil
+	}
+	return s, nil
+}
+
+// yamlRest checks that only a comment follows a quoted scalar.
+func yamlRest(rest []byte) error {
+	rest = bytes.TrimLeft(rest, " \t")
+	if len(rest) != 0 && rest[0] != '#' {
+		return fmt.Errorf("unexpected %q after quoted scalar", rest)
+	}
+	return nil
+}
+
+func yamlSingleQuoted(v []byte) (string, error) {
+	var b []byte
+	for i := 1; i < len(v); i++ {
+		if v[i] != '\'' {
+			b = append(b, v[i])
+			continue
+		}
+		if i+1 < len(v) && v[i+1] == '\'' {
+			b = append(b, '\'')
+			i++
+			continue
+		}
+		return string(b), yamlRest(v[i+1:])
+	}
+	return "", fmt.Errorf("unterminated or multi-line quoted scalar")
+}
+
+var yamlEscapes = map[byte]string{
+	'0': "\x00", 'a': "\a", 'b': "\b", 't': "\t", '\t': "\t", 'n': "\n",
+	'v': "\v", 'f': "\f", 'r': "\r", 'e': "\x1b", ' ': " ", '"': "\"",
+	'/': "/", '\\': "\\", 'N': "\u0085", '_': "\u00a0", 'L': "\u2028", 'P': "\u2029",
+}
+
+func yamlDoubleQuoted(v []byte) (string, error) {
+	var b []byte
+	for i := 1; i < len(v); i++ {
+		switch c := v[i]; c {
+		case '"':
+			return string(b), yamlRest(v[i+1:])
+		case '\\':
+			if i+1 >= len(v) {
+				break
+			}
+			i++
+			if s, ok := yamlEscapes[v[i]]; ok {
+				b = append(b, s...)
+				continue
+			}
+			var digits int
+			switch v[i] {
+			case 'x':
+				digits = 2
+			case 'u':
+				digits = 4
+			case 'U':
+				digits = 8
+			}
+			if digits == 0 || i+digits >= len(v) {
+				return "", fmt.Errorf("invalid escape in %q", v)
+			}
+			r, err := strconv.ParseUint(string(v[i+1:i+1+digits]), 16, 32)
+			if err != nil || !utf8.ValidRune(rune(r)) {
+				return "", fmt.Errorf("invalid escape in %q", v)
+			}
+			b = appendRune(b, rune(r))
+			i += digits
+		default:
+			b = append(b, c)
+		}
+	}
+	return "", fmt.Errorf("unterminated or multi-line quoted scalar")
+}
+
+// str returns the current key's value as a string; null reads as "".
+func (r *yamlReader) str() string {
+	s, err := yamlScalar(r.value)
+	if err != nil {
+		r.fail("%v", err)
+	}
+	if len(r.value) == 0 {
+		r.noBlock()
+	}
+	return s
+}
+
+// int returns the current key's value as a decimal integer.
+func (r *yamlReader) int() int64 {
+	v, err := yamlScalar(r.value)
+	if err == nil && len(r.value) > 0 && (r.value[0] == '\'' || r.value[0] == '"') {
+		err = fmt.Errorf("%s is a string, not an integer", r.value)
+	}
+	if err != nil {
+		r.fail("%v", err)
+		return 0
+	}
+	n, err := strconv.ParseInt(strings.TrimPrefix(v, "+"), 10, 64)
+	if err != nil {
+		r.fail("%s is not an integer", v)
+	}
+	return n
+}
+
+// noBlock fails if the current key is followed by an indented block, which
+// a scalar value cannot have.
+func (r *yamlReader) noBlock() {
+	if _, ok := r.item(); ok {
+		r.fail("unexpected sequence")
+	}
+}
+
+// strSeq appends the items of the current key's block sequence to dst. An
+// empty value or [] is an empty sequence.
+func (r *yamlReader) strSeq(dst []string) []string {
+	switch v := string(r.value); {
+	case v == "[]" || strings.HasPrefix(v, "[] #"):
+		return dst
+	case v != "" && v[0] != '#':
+		r.fail("expected a block sequence")
+		return dst
+	}
+	for {
+		item, ok := r.item()
+		if !ok {
+			return dst
+		}
+		s, err := yamlScalar(item)
+		if err != nil {
+			r.fail("%v", err)
+			return dst
+		}
+		dst = append(dst, s)
+	}
+}
+
+// item consumes the next line if it is a sequence item and returns its
+// value.
+func (r *yamlReader) item() ([]byte, bool) {
+	r.skipBlank()
+	if r.e != nil || r.line >= len(r.lines) {
+		return nil, false
+	}
+	l := bytes.TrimRight(bytes.TrimLeft(r.lines[r.line], " "), " \r")
+	if l[0] != '-' || (len(l) > 1 && l[1] != ' ') {
+		return nil, false
+	}
+	r.line++
+	return bytes.TrimLeft(l[1:], " "), true
+}
+
+// skip discards the current key's value, including any indented block
+// under it.
+func (r *yamlReader) skip() {
+	if len(r.value) != 0 && r.value[0] != '#' {
+		return
+	}
+	for {
+		r.skipBlank()
+		if r.line >= len(r.lines) {
+			return
+		}
+		l := r.lines[r.line]
+		if l[0] != ' ' && l[0] != '-' {
+			return
+		}
+		r.line++
+	}
+}
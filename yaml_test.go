@@ -0,0 +1,148 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// appendYAML appends a as a YAML document keyed by its json tags.
+func (a *AgentData) appendYAML(b []byte) []byte {
+	b = appendYAMLStringField(b, "hostname", a.Hostname)
+	b = appendYAMLStringField(b, "status", a.Status)
+	b = appendYAMLIntField(b, "timestamp", int64(a.Timestamp))
+	return appendYAMLStringSeqField(b, "lsns", a.Lsns)
+}
+
+// unmarshalYAML decodes a document of the subset yamlReader accepts into
+// a, skipping unknown keys.
+func (a *AgentData) unmarshalYAML(data []byte) error {
+	*a = AgentData{Lsns: a.Lsns[:0]}
+	r := newYAMLReader(data)
+	for {
+		key, ok := r.next()
+		if !ok {
+			break
+		}
+		switch key {
+		case "hostname":
+			a.Hostname = r.str()
+		case "status":
+			a.Status = r.str()
+		case "timestamp":
+			a.Timestamp = int(r.int())
+		case "lsns":
+			a.Lsns = r.strSeq(a.Lsns)
+		default:
+			r.skip()
+		}
+	}
+	if len(a.Lsns) == 0 {
+		a.Lsns = nil
+	}
+	return r.err()
+}
+
+func TestYAMLRoundTrip(t *testing.T) {
+	const doc = "hostname: 10.64.6.138\n" +
+		"status: In Progress\n" +
+		"timestamp: 1282368345\n" +
+		"lsns:\n" +
+		"  - 16/B374D848\n" +
+		"  - 16/B374D010\n"
+	if out := generateObject().appendYAML(nil); string(out) != doc {
+		t.Errorf("got\n%s\nwant\n%s", out, doc)
+	}
+
+	for _, want := range []*AgentData{
+		generateObject(),
+		{},
+		{
+			Hostname:  "- a: b #c",
+			Status:    "line\nbreak \"quoted\" \\ \t\x01 é\u2028\U000e0001 ",
+			Timestamp: -1,
+			Lsns:      []string{"", "true", "1.5", "0x1f", "null", "'", "[a]", "~", "1_000", "-1:30", "2e-3", "1.2.3"},
+		},
+	} {
+		out := want.appendYAML(nil)
+		got := &AgentData{}
+		if err := got.unmarshalYAML(out); err != nil {
+			t.Fatalf("%s: %v", out, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %+v, want %+v", out, got, want)
+		}
+	}
+}
+
+func TestYAMLDecode(t *testing.T) {
+	const doc = "---\n" +
+		"# agent status\n" +
+		"hostname: 'it''s' # the host\n" +
+		"extra:\n" +
+		"  nested: value\n" +
+		"  - item\n" +
+		"\n" +
+		"status: \"\\x41\\u00e9\\U0001F600\\_\"\n" +
+		"other: plain value\n" +
+		"timestamp: +42\n" +
+		"lsns: # the log sequence numbers\n" +
+		"- a\n" +
+		"-   b c   # comment\n" +
+		"- ~\n"
+	want := &AgentData{
+		Hostname:  "it's",
+		Status:    "Aé\U0001F600\u00a0",
+		Timestamp: 42,
+		Lsns:      []string{"a", "b c", ""},
+	}
+	got := &AgentData{}
+	if err := got.unmarshalYAML([]byte(doc)); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	for _, bad := range []string{
+		"hostname: [a, b]\n",
+		"hostname: 'unterminated\n",
+		"hostname: \"a\" b\n",
+		"hostname: \"\\q\"\n",
+		"timestamp: '1'\n",
+		"timestamp: 1.5\n",
+		"lsns: a\n",
+		"hostname:\n  - a\n",
+		"  hostname: a\n",
+		"hostname\n",
+		"hostname:a\n",
+	} {
+		if err := got.unmarshalYAML([]byte(bad)); err == nil {
+			t.Errorf("%q decoded without error", bad)
+		}
+	}
+}
+
+func BenchmarkYAMLMarshal(b *testing.B) {
+	obj := generateObject()
+	var out []byte
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		out = obj.appendYAML(out[:0])
+	}
+	b.ReportMetric(float64(len(out)), "bytes")
+}
+
+func BenchmarkYAMLUnmarshal(b *testing.B) {
+	out := generateObject().appendYAML(nil)
+
+	obj := &AgentData{}
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		err := obj.unmarshalYAML(out)
+		if err != nil {
+			panic(err)
+		}
+	}
+}